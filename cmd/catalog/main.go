@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"mini-ecommerce/config"
+	database "mini-ecommerce/db"
+	"mini-ecommerce/internal/category"
+	"mini-ecommerce/internal/product"
+	"mini-ecommerce/internal/sale"
+	"mini-ecommerce/pkg/money"
+	"mini-ecommerce/pkg/storage"
+)
+
+const usage = `Bulk product import and export.
+
+Usage:
+  catalog import [-format csv|jsonl] [-dry-run] FILE
+  catalog export [-format csv|jsonl] [-o FILE]
+
+The import format defaults to the one named by FILE's extension. Export
+writes CSV to standard output unless told otherwise.
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg := config.LoadConfig()
+	money.SetDefaultCurrency(cfg.Currency)
+
+	switch os.Args[1] {
+	case "import":
+		runImport(cfg, os.Args[2:])
+	case "export":
+		runExport(cfg, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runImport(cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "csv or jsonl")
+	dryRun := flags.Bool("dry-run", false, "validate without saving")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = product.FormatForFilename(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	report, err := productService(cfg).ImportProducts(file, *format, *dryRun, 0)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runExport(cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", product.FormatCSV, "csv or jsonl")
+	output := flags.String("o", "", "file to write instead of standard output")
+	flags.Parse(args)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := productService(cfg).ExportProducts(w, *format); err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+}
+
+// productService connects to the database and builds the service the API uses
+func productService(cfg config.Config) product.ProductService {
+	db := database.Connect(cfg)
+	store, err := storage.New(cfg.StorageDriver, cfg.StorageDir, cfg.StorageBaseURL)
+	if err != nil {
+		log.Fatalf("Failed to set up storage: %v", err)
+	}
+	productRepo := product.NewProductRepository(db)
+	categoryRepo := category.NewCategoryRepository(db)
+	sales := sale.NewSaleService(sale.NewSaleRepository(db), productRepo, categoryRepo)
+	return product.NewProductService(productRepo, categoryRepo, store, sales)
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +18,17 @@ type Config struct {
 	DBHost   string
 	DBPort   string
 	DBName   string
+
+	Currency             string // ISO 4217 code used for all prices
+	PaymentProvider      string // payment gateway name; required
+	PaymentWebhookSecret string // required, and not one of placeholderSecrets
+	// PaymentFakeEnabled allows the in-process "fake" provider, which lets
+	// customers mark their own orders paid. For development only.
+	PaymentFakeEnabled bool
+
+	StorageDriver  string // where uploads are kept, "local" for StorageDir
+	StorageDir     string
+	StorageBaseURL string // URL uploads are served from; a path is served by this server
 }
 
 func LoadConfig() Config {
@@ -32,12 +46,53 @@ func LoadConfig() Config {
 		DBHost:   getEnv("DB_HOST", "localhost"),
 		DBPort:   getEnv("DB_PORT", "5432"),
 		DBName:   getEnv("DB_NAME", "ecommerce"),
+
+		Currency:             getEnv("CURRENCY", "BDT"),
+		PaymentProvider:      getEnv("PAYMENT_PROVIDER", ""),
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+		PaymentFakeEnabled:   getBool("PAYMENT_FAKE_ENABLED", false),
+
+		StorageDriver:  getEnv("STORAGE_DRIVER", "local"),
+		StorageDir:     getEnv("STORAGE_DIR", "uploads"),
+		StorageBaseURL: getEnv("STORAGE_BASE_URL", "/uploads"),
 	}
 }
 
+// placeholderSecrets are webhook secrets from examples and earlier defaults,
+// which anyone could use to forge webhooks
+var placeholderSecrets = []string{"dev-webhook-secret", "change-me"}
+
+// CheckPayments reports payment settings the server must not start with
+func (c Config) CheckPayments() error {
+	if c.PaymentProvider == "" {
+		return errors.New("PAYMENT_PROVIDER is required")
+	}
+	if c.PaymentProvider == "fake" && !c.PaymentFakeEnabled {
+		return errors.New(`PAYMENT_PROVIDER "fake" is for development and needs PAYMENT_FAKE_ENABLED=true`)
+	}
+	if c.PaymentWebhookSecret == "" {
+		return errors.New("PAYMENT_WEBHOOK_SECRET is required")
+	}
+	for _, placeholder := range placeholderSecrets {
+		if c.PaymentWebhookSecret == placeholder {
+			return fmt.Errorf("PAYMENT_WEBHOOK_SECRET must not be the example value %q", placeholder)
+		}
+	}
+	return nil
+}
+
 func getEnv(key, defaultVal string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return defaultVal
 }
+
+func getBool(key string, defaultVal bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(defaultVal)))
+	if err != nil {
+		log.Printf("Invalid %s, using %t", key, defaultVal)
+		return defaultVal
+	}
+	return value
+}
@@ -3,15 +3,26 @@ package database
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	"mini-ecommerce/config"
 	"mini-ecommerce/internal/admin"
+	"mini-ecommerce/internal/cart"
+	"mini-ecommerce/internal/category"
 	"mini-ecommerce/internal/order"
+	"mini-ecommerce/internal/payment"
 	"mini-ecommerce/internal/product"
+	"mini-ecommerce/internal/promotion"
+	"mini-ecommerce/internal/returns"
+	"mini-ecommerce/internal/review"
+	"mini-ecommerce/internal/sale"
+	"mini-ecommerce/internal/shipping"
+	"mini-ecommerce/internal/tax"
 	"mini-ecommerce/internal/user"
+	"mini-ecommerce/pkg/money"
 )
 
 func Connect(cfg config.Config) *gorm.DB {
@@ -34,10 +45,285 @@ func Connect(cfg config.Config) *gorm.DB {
 }
 
 func Migrate(db *gorm.DB) error {
-	if err := db.AutoMigrate(&product.Product{}, &admin.Admin{}, &user.User{}, &order.Order{}); err != nil {
+	if err := migrateMoneyColumns(db); err != nil {
+		log.Fatalf("Money columns migration failed: %v", err)
+		return err
+	}
+	if err := addOrderSubtotals(db); err != nil {
+		log.Fatalf("Order subtotal migration failed: %v", err)
+		return err
+	}
+
+	err := db.AutoMigrate(
+		&category.Category{},
+		&product.Product{},
+		&product.Variant{},
+		&product.Image{},
+		&product.PriceChange{},
+		&review.Review{},
+		&sale.Sale{},
+		&sale.SaleProduct{},
+		&sale.SaleCategory{},
+		&admin.Admin{},
+		&user.User{},
+		&order.Order{},
+		&order.OrderItem{},
+		&order.OrderStatusHistory{},
+		&cart.Cart{},
+		&cart.CartItem{},
+		&payment.Payment{},
+		&returns.ReturnRequest{},
+		&returns.ReturnItem{},
+		&returns.Refund{},
+		&promotion.Coupon{},
+		&promotion.CouponProduct{},
+		&promotion.CouponRedemption{},
+		&tax.TaxRule{},
+		&order.OrderTax{},
+		&shipping.Zone{},
+		&shipping.ZoneRegion{},
+		&shipping.Method{},
+		&shipping.WeightRate{},
+	)
+	if err != nil {
 		log.Fatalf("Auto migration failed: %v", err)
 		return err
 	}
+	if err := dropLegacyCartItemIndex(db); err != nil {
+		log.Fatalf("Cart item index migration failed: %v", err)
+		return err
+	}
+	if err := migrateOrderItems(db); err != nil {
+		log.Fatalf("Order items migration failed: %v", err)
+		return err
+	}
+	if err := backfillCurrencies(db); err != nil {
+		log.Fatalf("Currency backfill failed: %v", err)
+		return err
+	}
+	if err := backfillSearchVectors(db); err != nil {
+		log.Fatalf("Search vector backfill failed: %v", err)
+		return err
+	}
+	if err := backfillPriceHistory(db); err != nil {
+		log.Fatalf("Price history backfill failed: %v", err)
+		return err
+	}
+	if err := restrictOrderedProductDeletes(db); err != nil {
+		log.Fatalf("Order item foreign key migration failed: %v", err)
+		return err
+	}
 	log.Println("Database migration completed successfully")
 	return nil
 }
+
+// migrateOrderItems moves orders created before line items existed, which kept
+// a single product_id/quantity on the order row, into order_items and drops the
+// old columns.
+func migrateOrderItems(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("orders", "product_id") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO order_items (order_id, product_id, name, price_amount, price_currency,
+			                         quantity, total_amount, total_currency)
+			SELECT o.id, o.product_id, COALESCE(p.name, ''),
+			       CASE WHEN o.quantity > 0 THEN o.total_price_amount / o.quantity ELSE o.total_price_amount END,
+			       o.total_price_currency, o.quantity, o.total_price_amount, o.total_price_currency
+			FROM orders o
+			LEFT JOIN products p ON p.id = o.product_id
+			WHERE NOT EXISTS (SELECT 1 FROM order_items i WHERE i.order_id = o.id)`).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn("orders", "product_id"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn("orders", "quantity")
+	})
+}
+
+// restrictOrderedProductDeletes stops deleting a product from deleting the
+// order lines that include it. Databases set up from the SQL scripts before
+// products were soft-deleted have a cascading foreign key.
+func restrictOrderedProductDeletes(db *gorm.DB) error {
+	var rule string
+	err := db.Raw(`SELECT delete_rule FROM information_schema.referential_constraints
+		WHERE constraint_name = 'order_items_product_id_fkey'`).Scan(&rule).Error
+	if err != nil || rule != "CASCADE" {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE order_items DROP CONSTRAINT order_items_product_id_fkey").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE order_items ADD CONSTRAINT order_items_product_id_fkey
+			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT`).Error
+	})
+}
+
+// dropLegacyCartItemIndex drops the one-row-per-product unique index that
+// cart items had before variants, which would stop a cart holding two
+// variants of a product
+func dropLegacyCartItemIndex(db *gorm.DB) error {
+	return db.Exec("DROP INDEX IF EXISTS idx_cart_items_cart_product").Error
+}
+
+// addOrderSubtotals adds the subtotal to orders placed before discounts
+// existed, where the total was the subtotal. It runs before AutoMigrate and
+// only while the column is missing, so subtotals are never rewritten once set.
+func addOrderSubtotals(db *gorm.DB) error {
+	if !db.Migrator().HasTable("orders") || db.Migrator().HasColumn("orders", "subtotal_amount") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE orders ADD COLUMN subtotal_amount BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE orders ADD COLUMN subtotal_currency VARCHAR(3)`,
+			`UPDATE orders SET subtotal_amount = total_price_amount, subtotal_currency = total_price_currency`,
+		}
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// moneyColumn maps a legacy DECIMAL column to the <prefix>amount and
+// <prefix>currency columns of an embedded money.Money
+type moneyColumn struct {
+	table  string
+	column string
+	prefix string
+}
+
+var moneyColumns = []moneyColumn{
+	{"products", "price", "price_"},
+	{"orders", "subtotal", "subtotal_"},
+	{"orders", "discount", "discount_"},
+	{"orders", "tax_total", "tax_total_"},
+	{"orders", "shipping_cost", "shipping_cost_"},
+	{"orders", "total_price", "total_price_"},
+	{"order_items", "price", "price_"},
+	{"order_items", "total", "total_"},
+	{"order_items", "discount", "discount_"},
+	{"order_items", "tax_amount", "tax_amount_"},
+	{"order_taxes", "taxable", "taxable_"},
+	{"order_taxes", "amount", ""},
+	{"payments", "amount", ""},
+	{"payments", "refunded_amount", "refunded_"},
+	{"return_items", "amount", ""},
+	{"refunds", "amount", ""},
+	{"coupons", "min_spend", "min_spend_"},
+	{"coupon_redemptions", "discount", "discount_"},
+	{"shipping_methods", "flat_fee", "flat_fee_"},
+	{"shipping_methods", "free_threshold", "free_threshold_"},
+	{"shipping_rates", "price", "price_"},
+}
+
+// migrateMoneyColumns converts prices stored as DECIMAL major units into
+// money.Money columns holding minor units and a currency code. It runs before
+// AutoMigrate, since some new columns reuse the old names (payments.amount).
+func migrateMoneyColumns(db *gorm.DB) error {
+	currency := money.DefaultCurrency()
+	for _, mc := range moneyColumns {
+		if !isDecimalColumn(db, mc.table, mc.column) {
+			continue
+		}
+
+		legacy := mc.column + "_legacy"
+		amount := mc.prefix + "amount"
+		code := mc.prefix + "currency"
+		err := db.Transaction(func(tx *gorm.DB) error {
+			statements := []string{
+				fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN %s TO %s`, mc.table, mc.column, legacy),
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s BIGINT NOT NULL DEFAULT 0`, mc.table, amount),
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s VARCHAR(3)`, mc.table, code),
+				fmt.Sprintf(`UPDATE %s SET %s = ROUND(COALESCE(%s, 0) * 100), %s = COALESCE(NULLIF(%s, ''), '%s')`,
+					mc.table, amount, legacy, code, code, currency),
+				fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, mc.table, legacy),
+			}
+			for _, stmt := range statements {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", mc.table, mc.column, err)
+		}
+	}
+
+	// Fixed coupons kept their amount in value; it moves to amount_amount
+	if isDecimalColumn(db, "coupons", "value") && !db.Migrator().HasColumn("coupons", "amount_amount") {
+		return db.Transaction(func(tx *gorm.DB) error {
+			statements := []string{
+				`ALTER TABLE coupons ADD COLUMN amount_amount BIGINT NOT NULL DEFAULT 0`,
+				`ALTER TABLE coupons ADD COLUMN amount_currency VARCHAR(3)`,
+				fmt.Sprintf(`UPDATE coupons SET amount_amount = ROUND(value * 100), amount_currency = '%s', value = 0
+					WHERE type = 'fixed'`, currency),
+			}
+			for _, stmt := range statements {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// backfillCurrencies sets the default currency on money columns that were
+// added to existing rows without one
+func backfillCurrencies(db *gorm.DB) error {
+	columns := append([]moneyColumn{{"coupons", "value", "amount_"}}, moneyColumns...)
+	for _, mc := range columns {
+		code := mc.prefix + "currency"
+		err := db.Exec(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s IS NULL OR %s = ''`, mc.table, code, code, code),
+			money.DefaultCurrency()).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSearchVectors indexes products saved before full-text search
+// existed, or written directly to the table
+func backfillSearchVectors(db *gorm.DB) error {
+	return db.Exec("UPDATE products SET search_vector = " + product.SearchVectorSQL + " WHERE search_vector IS NULL").Error
+}
+
+// backfillPriceHistory opens the price history of products created before it
+// was kept, with their current price taking effect when they were created
+func backfillPriceHistory(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO product_price_history (product_id, price_amount, price_currency, effective_from, changed_by)
+		SELECT id, price_amount, price_currency, created_at, 0 FROM products p
+		WHERE NOT EXISTS (SELECT 1 FROM product_price_history h WHERE h.product_id = p.id)`).Error
+}
+
+// isDecimalColumn reports whether table.column exists with a NUMERIC type,
+// i.e. still holds a price in major units
+func isDecimalColumn(db *gorm.DB, table, column string) bool {
+	if !db.Migrator().HasColumn(table, column) {
+		return false
+	}
+	types, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if t.Name() == column {
+			name := strings.ToLower(t.DatabaseTypeName())
+			return name == "numeric" || name == "decimal"
+		}
+	}
+	return false
+}
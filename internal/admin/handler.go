@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -98,7 +99,7 @@ func (h *AdminHandler) GetAllAdmins(c *gin.Context) {
 	c.JSON(http.StatusOK, responses)
 }
 
-// UpdateAdmin updates an admin
+// UpdateAdmin replaces an admin's username, email and role
 func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -106,15 +107,41 @@ func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
 		return
 	}
 
-	var admin Admin
-	if err := c.BindJSON(&admin); err != nil {
+	var req AdminUpdateRequest
+	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	updatedAdmin, err := h.service.UpdateAdmin(id, &admin)
+	updatedAdmin, err := h.service.UpdateAdmin(id, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update admin"})
+		respondUpdateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Admin updated successfully",
+		"admin":   updatedAdmin.ToResponse(),
+	})
+}
+
+// PatchAdmin changes the details of an admin given in a JSON Merge Patch
+func (h *AdminHandler) PatchAdmin(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	var req AdminPatchRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	updatedAdmin, err := h.service.PatchAdmin(id, req)
+	if err != nil {
+		respondUpdateError(c, err)
 		return
 	}
 
@@ -140,3 +167,15 @@ func (h *AdminHandler) DeleteAdmin(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Admin deleted successfully"})
 }
+
+// respondUpdateError maps errors from updating an admin to status codes
+func respondUpdateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrAdminNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+	case errors.Is(err, ErrInvalidPatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update admin"})
+	}
+}
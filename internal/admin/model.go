@@ -1,5 +1,7 @@
 package admin
 
+import "mini-ecommerce/pkg/patch"
+
 type Admin struct {
 	ID       int    `json:"id" gorm:"primaryKey"`
 	Username string `json:"username" gorm:"uniqueIndex"`
@@ -27,6 +29,22 @@ type AdminRegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+// AdminUpdateRequest replaces an admin's details. The password is changed
+// separately.
+type AdminUpdateRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Role     string `json:"role" binding:"required,oneof=admin super_admin"`
+}
+
+// AdminPatchRequest is a JSON Merge Patch of an admin's details. None of
+// them can be null.
+type AdminPatchRequest struct {
+	Username patch.Field[string] `json:"username"`
+	Email    patch.Field[string] `json:"email"`
+	Role     patch.Field[string] `json:"role"`
+}
+
 // ToResponse converts Admin to AdminResponse (removes password)
 func (a *Admin) ToResponse() *AdminResponse {
 	return &AdminResponse{
@@ -2,15 +2,26 @@ package admin
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin/binding"
 
 	"mini-ecommerce/pkg/middleware"
+	"mini-ecommerce/pkg/validation"
+)
+
+var (
+	ErrAdminNotFound = errors.New("admin not found")
+	// ErrInvalidPatch is returned for patches that leave an admin invalid
+	ErrInvalidPatch = errors.New("invalid patch")
 )
 
 type AdminService interface {
 	Register(req AdminRegisterRequest) (*Admin, error)
 	Login(req AdminLoginRequest) (map[string]interface{}, error)
 	GetAdminByID(id int) (*Admin, error)
-	UpdateAdmin(id int, admin *Admin) (*Admin, error)
+	UpdateAdmin(id int, req AdminUpdateRequest) (*Admin, error)
+	PatchAdmin(id int, req AdminPatchRequest) (*Admin, error)
 	DeleteAdmin(id int) error
 	GetAllAdmins() ([]Admin, error)
 }
@@ -82,14 +93,38 @@ func (s *adminService) GetAdminByID(id int) (*Admin, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *adminService) UpdateAdmin(id int, admin *Admin) (*Admin, error) {
-	err := s.repo.Update(id, admin)
+// UpdateAdmin replaces an admin's username, email and role
+func (s *adminService) UpdateAdmin(id int, req AdminUpdateRequest) (*Admin, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, ErrAdminNotFound
+	}
+	err := s.repo.Update(id, &Admin{Username: req.Username, Email: req.Email, Role: req.Role})
 	if err != nil {
 		return nil, err
 	}
 	return s.repo.FindByID(id)
 }
 
+// PatchAdmin applies a JSON Merge Patch to an admin's details
+func (s *adminService) PatchAdmin(id int, req AdminPatchRequest) (*Admin, error) {
+	admin, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrAdminNotFound
+	}
+	if req.Username.Null || req.Email.Null || req.Role.Null {
+		return nil, fmt.Errorf("%w: username, email and role cannot be null", ErrInvalidPatch)
+	}
+
+	merged := AdminUpdateRequest{Username: admin.Username, Email: admin.Email, Role: admin.Role}
+	req.Username.Apply(&merged.Username)
+	req.Email.Apply(&merged.Email)
+	req.Role.Apply(&merged.Role)
+	if err := binding.Validator.ValidateStruct(&merged); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, validation.Describe(err))
+	}
+	return s.UpdateAdmin(id, merged)
+}
+
 func (s *adminService) DeleteAdmin(id int) error {
 	return s.repo.Delete(id)
 }
@@ -0,0 +1,138 @@
+package cart
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/internal/product"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CartHandler struct {
+	service CartService
+}
+
+func NewCartHandler(service CartService) *CartHandler {
+	return &CartHandler{service: service}
+}
+
+// GetCart retrieves the authenticated user's cart with current prices
+func (h *CartHandler) GetCart(c *gin.Context) {
+	cart, err := h.service.GetCart(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// AddItem adds a product to the cart
+func (h *CartHandler) AddItem(c *gin.Context) {
+	var req AddItemRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	cart, err := h.service.AddItem(c.GetInt("userID"), req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// UpdateItem changes the quantity of a product, or of the variant given by
+// ?variant_id=, in the cart
+func (h *CartHandler) UpdateItem(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.DefaultQuery("variant_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	var req UpdateItemRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	cart, err := h.service.UpdateItem(c.GetInt("userID"), productID, variantID, req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// RemoveItem removes a product, or the variant given by ?variant_id=, from the cart
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.DefaultQuery("variant_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	cart, err := h.service.RemoveItem(c.GetInt("userID"), productID, variantID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// ClearCart removes every item from the cart
+func (h *CartHandler) ClearCart(c *gin.Context) {
+	if err := h.service.ClearCart(c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared successfully"})
+}
+
+// Checkout converts the cart into an order and empties it
+func (h *CartHandler) Checkout(c *gin.Context) {
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	placed, err := h.service.Checkout(c.GetInt("userID"), req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, placed)
+}
+
+func (h *CartHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrItemNotFound), errors.Is(err, product.ErrVariantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, order.ErrInsufficientStock), errors.Is(err, ErrCartChanged), errors.Is(err, ErrUnavailableItems):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,74 @@
+package cart
+
+import (
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Cart holds the products a user intends to buy. Each user has at most one cart.
+type Cart struct {
+	ID        int        `json:"id" gorm:"primaryKey"`
+	UserID    int        `json:"user_id" gorm:"uniqueIndex"`
+	Items     []CartItem `json:"items" gorm:"foreignKey:CartID"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CartItem stores only the product, variant and quantity; prices are looked
+// up live whenever the cart is read or checked out.
+type CartItem struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	CartID    int       `json:"cart_id" gorm:"uniqueIndex:idx_cart_items_cart_product_variant"`
+	ProductID int       `json:"product_id" gorm:"uniqueIndex:idx_cart_items_cart_product_variant"`
+	VariantID int       `json:"variant_id" gorm:"not null;default:0;uniqueIndex:idx_cart_items_cart_product_variant"` // 0 for products without variants
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AddItemRequest struct {
+	ProductID int `json:"product_id" binding:"required,gt=0"`
+	VariantID int `json:"variant_id" binding:"omitempty,gt=0"` // required for products with variants
+	Quantity  int `json:"quantity" binding:"required,gt=0"`
+}
+
+type UpdateItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,gt=0"`
+}
+
+type CheckoutRequest struct {
+	CouponCode       string `json:"coupon_code"`
+	ShippingRegion   string `json:"shipping_region"`
+	ShippingMethodID int    `json:"shipping_method_id" binding:"omitempty,gt=0"`
+}
+
+type CartItemResponse struct {
+	ProductID int         `json:"product_id"`
+	VariantID int         `json:"variant_id,omitempty"`
+	SKU       string      `json:"sku,omitempty"`
+	Name      string      `json:"name"`
+	Price     money.Money `json:"price"`
+	Quantity  int         `json:"quantity"`
+	Total     money.Money `json:"total"`
+}
+
+// UnavailableItemResponse is a cart line that can no longer be bought, such
+// as a product that was unpublished or a variant that was deleted. Checkout
+// fails until it is removed.
+type UnavailableItemResponse struct {
+	ProductID int    `json:"product_id"`
+	VariantID int    `json:"variant_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Quantity  int    `json:"quantity"`
+	Reason    string `json:"reason"`
+}
+
+type CartResponse struct {
+	ID          int                       `json:"id"`
+	UserID      int                       `json:"user_id"`
+	Items       []CartItemResponse        `json:"items"`
+	Unavailable []UnavailableItemResponse `json:"unavailable"`
+	TotalPrice  money.Money               `json:"total_price"`
+	UpdatedAt   time.Time                 `json:"updated_at"`
+}
@@ -0,0 +1,95 @@
+package cart
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartRepository interface {
+	FindOrCreateByUserID(userID int) (*Cart, error)
+	AddItem(cartID int, productID int, variantID int, quantity int) error
+	UpdateItem(cartID int, productID int, variantID int, quantity int) error
+	RemoveItem(cartID int, productID int, variantID int) error
+	Clear(cartID int) error
+	ClearCheckedOut(cartID int, itemCount int) error
+}
+
+type cartRepository struct {
+	db *gorm.DB
+}
+
+func NewCartRepository(db *gorm.DB) CartRepository {
+	return &cartRepository{db: db}
+}
+
+func (r *cartRepository) FindOrCreateByUserID(userID int) (*Cart, error) {
+	var cart Cart
+	err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where(Cart{UserID: userID}).FirstOrCreate(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// AddItem inserts the product variant or increases its quantity if it is already in the cart
+func (r *cartRepository) AddItem(cartID int, productID int, variantID int, quantity int) error {
+	item := &CartItem{CartID: cartID, ProductID: productID, VariantID: variantID, Quantity: quantity}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "cart_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   gorm.Expr("cart_items.quantity + ?", quantity),
+			"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		}),
+	}).Create(item).Error
+}
+
+func (r *cartRepository) UpdateItem(cartID int, productID int, variantID int, quantity int) error {
+	result := r.db.Model(&CartItem{}).
+		Where("cart_id = ? AND product_id = ? AND variant_id = ?", cartID, productID, variantID).
+		Update("quantity", quantity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *cartRepository) RemoveItem(cartID int, productID int, variantID int) error {
+	result := r.db.Where("cart_id = ? AND product_id = ? AND variant_id = ?", cartID, productID, variantID).Delete(&CartItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *cartRepository) Clear(cartID int) error {
+	return r.db.Where("cart_id = ?", cartID).Delete(&CartItem{}).Error
+}
+
+// ClearCheckedOut empties a cart that is being checked out, and must run in
+// the order's transaction. The cart row is locked first, so a second checkout
+// of the same cart waits, then finds the items gone and fails with
+// ErrCartChanged instead of placing another order.
+func (r *cartRepository) ClearCheckedOut(cartID int, itemCount int) error {
+	var cart Cart
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&cart, cartID).Error
+	if err != nil {
+		return err
+	}
+
+	result := r.db.Where("cart_id = ?", cartID).Delete(&CartItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(itemCount) {
+		return ErrCartChanged
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/internal/product"
+	"mini-ecommerce/pkg/money"
+)
+
+var (
+	ErrEmptyCart       = errors.New("cart is empty")
+	ErrItemNotFound    = errors.New("item not in cart")
+	ErrProductNotFound = errors.New("product not found")
+	ErrCartChanged     = errors.New("cart changed during checkout; please review it and try again")
+	// ErrUnavailableItems is returned when checking out a cart holding items
+	// that can no longer be bought
+	ErrUnavailableItems = errors.New("some items in the cart are no longer available; remove them and try again")
+)
+
+type CartService interface {
+	GetCart(userID int) (*CartResponse, error)
+	AddItem(userID int, req AddItemRequest) (*CartResponse, error)
+	UpdateItem(userID int, productID int, variantID int, req UpdateItemRequest) (*CartResponse, error)
+	RemoveItem(userID int, productID int, variantID int) (*CartResponse, error)
+	ClearCart(userID int) error
+	Checkout(userID int, req CheckoutRequest) (*order.Order, error)
+}
+
+type cartService struct {
+	repo         CartRepository
+	productRepo  product.ProductRepository
+	orderService order.OrderService
+	sales        product.SalePricer
+}
+
+func NewCartService(repo CartRepository, productRepo product.ProductRepository, orderService order.OrderService, sales product.SalePricer) CartService {
+	return &cartService{
+		repo:         repo,
+		productRepo:  productRepo,
+		orderService: orderService,
+		sales:        sales,
+	}
+}
+
+func (s *cartService) GetCart(userID int) (*CartResponse, error) {
+	cart, err := s.repo.FindOrCreateByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(cart), nil
+}
+
+func (s *cartService) AddItem(userID int, req AddItemRequest) (*CartResponse, error) {
+	// Verify product and variant exist, and the product is on sale to customers
+	prod, err := s.productRepo.FindByID(req.ProductID)
+	if err != nil || !prod.IsPublished(time.Now()) {
+		return nil, ErrProductNotFound
+	}
+	if _, err := prod.Select(req.VariantID); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.repo.FindOrCreateByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddItem(cart.ID, req.ProductID, req.VariantID, req.Quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(userID)
+}
+
+func (s *cartService) UpdateItem(userID int, productID int, variantID int, req UpdateItemRequest) (*CartResponse, error) {
+	cart, err := s.repo.FindOrCreateByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.UpdateItem(cart.ID, productID, variantID, req.Quantity)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCart(userID)
+}
+
+func (s *cartService) RemoveItem(userID int, productID int, variantID int) (*CartResponse, error) {
+	cart, err := s.repo.FindOrCreateByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.RemoveItem(cart.ID, productID, variantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCart(userID)
+}
+
+func (s *cartService) ClearCart(userID int) error {
+	cart, err := s.repo.FindOrCreateByUserID(userID)
+	if err != nil {
+		return err
+	}
+	return s.repo.Clear(cart.ID)
+}
+
+// Checkout turns the cart into an order. The order insert and emptying the
+// cart happen in one transaction, so a failed order leaves the cart intact,
+// and submitting the same cart twice only places one order.
+func (s *cartService) Checkout(userID int, checkout CheckoutRequest) (*order.Order, error) {
+	cart, err := s.repo.FindOrCreateByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+	// Refuse what the cart view lists as unavailable, rather than failing
+	// on it somewhere in the order
+	var unavailable []string
+	for _, item := range cart.Items {
+		if _, u := s.resolve(item); u != nil {
+			name := u.Name
+			if name == "" {
+				name = fmt.Sprintf("product %d", u.ProductID)
+			}
+			unavailable = append(unavailable, name+": "+u.Reason)
+		}
+	}
+	if len(unavailable) > 0 {
+		return nil, fmt.Errorf("%w (%s)", ErrUnavailableItems, strings.Join(unavailable, "; "))
+	}
+
+	req := order.CreateOrderRequest{
+		UserID:           userID,
+		CouponCode:       checkout.CouponCode,
+		ShippingRegion:   checkout.ShippingRegion,
+		ShippingMethodID: checkout.ShippingMethodID,
+	}
+	for _, item := range cart.Items {
+		req.Items = append(req.Items, order.OrderItemRequest{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return s.orderService.CreateOrder(req, s.productRepo, func(tx *gorm.DB, _ *order.Order) error {
+		return NewCartRepository(tx).ClearCheckedOut(cart.ID, len(cart.Items))
+	})
+}
+
+// toResponse prices every item using the current product price, or its sale
+// price, as checkout will
+func (s *cartService) toResponse(cart *Cart) *CartResponse {
+	resp := &CartResponse{
+		ID:          cart.ID,
+		UserID:      cart.UserID,
+		Items:       []CartItemResponse{},
+		Unavailable: []UnavailableItemResponse{},
+		TotalPrice:  money.Zero(money.DefaultCurrency()),
+		UpdatedAt:   cart.UpdatedAt,
+	}
+
+	for _, item := range cart.Items {
+		sel, unavailable := s.resolve(item)
+		if unavailable != nil {
+			resp.Unavailable = append(resp.Unavailable, *unavailable)
+			continue
+		}
+
+		total := sel.Price.Mul(item.Quantity)
+		resp.Items = append(resp.Items, CartItemResponse{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			SKU:       sel.SKU,
+			Name:      sel.Name,
+			Price:     sel.Price,
+			Quantity:  item.Quantity,
+			Total:     total,
+		})
+		resp.TotalPrice = resp.TotalPrice.Add(total)
+	}
+
+	return resp
+}
+
+// resolve looks up what a cart item would buy, at its sale price if it has
+// one, or why it can no longer be bought
+func (s *cartService) resolve(item CartItem) (*product.Selection, *UnavailableItemResponse) {
+	unavailable := &UnavailableItemResponse{
+		ProductID: item.ProductID,
+		VariantID: item.VariantID,
+		Quantity:  item.Quantity,
+	}
+	prod, err := s.productRepo.FindByID(item.ProductID)
+	if err != nil {
+		unavailable.Reason = "no longer sold"
+		return nil, unavailable
+	}
+	unavailable.Name = prod.Name
+	if !prod.IsPublished(time.Now()) {
+		unavailable.Reason = "not available right now"
+		return nil, unavailable
+	}
+	if err := s.sales.ApplySales([]*product.Product{prod}); err != nil {
+		log.Printf("product %d: sale prices not applied to cart: %v", prod.ID, err)
+	}
+	sel, err := prod.Select(item.VariantID)
+	if errors.Is(err, product.ErrVariantRequired) {
+		// Variants were added since it was put in the cart
+		unavailable.Reason = "choose a variant"
+		return nil, unavailable
+	}
+	if err != nil {
+		unavailable.Reason = "variant no longer sold"
+		return nil, unavailable
+	}
+	return sel, nil
+}
@@ -0,0 +1,131 @@
+package category
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	service CategoryService
+}
+
+func NewCategoryHandler(service CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: service}
+}
+
+// GetCategoryTree retrieves all categories as a tree (accessible to customers)
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.service.GetCategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetCategory retrieves a category and its subcategories by ID or slug
+// (accessible to customers)
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	category, err := h.service.GetCategory(c.Param("id"))
+	if errors.Is(err, ErrCategoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// CreateCategory creates a category (admin only)
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	category, err := h.service.CreateCategory(req)
+	if err != nil {
+		respondError(c, err, "Failed to create category")
+		return
+	}
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory replaces a category's name, slug, description and parent (admin only)
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req CategoryRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	category, err := h.service.UpdateCategory(id, req)
+	if err != nil {
+		respondError(c, err, "Failed to update category")
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// MoveCategory moves a category to another parent and/or position (admin only)
+func (h *CategoryHandler) MoveCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req MoveCategoryRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	category, err := h.service.MoveCategory(id, req)
+	if err != nil {
+		respondError(c, err, "Failed to move category")
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory deletes a category without subcategories (admin only)
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := h.service.DeleteCategory(id); err != nil {
+		respondError(c, err, "Failed to delete category")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// respondError maps category errors to status codes
+func respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrCategoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+	case errors.Is(err, ErrSlugTaken), errors.Is(err, ErrCategoryHasChildren):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidSlug), errors.Is(err, ErrInvalidParent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
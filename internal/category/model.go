@@ -0,0 +1,52 @@
+package category
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Category groups products. Categories form a tree through ParentID, and
+// siblings are listed in Position order.
+type Category struct {
+	ID          int        `json:"id" gorm:"primaryKey"`
+	ParentID    *int       `json:"parent_id" gorm:"index"` // nil for top-level categories
+	Name        string     `json:"name" gorm:"not null"`
+	Slug        string     `json:"slug" gorm:"uniqueIndex;not null"` // used in storefront URLs
+	Description string     `json:"description"`
+	Position    int        `json:"position" gorm:"not null;default:0"` // among siblings, from 0
+	Children    []Category `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type CategoryRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Slug        string `json:"slug"` // defaults to one made from the name
+	Description string `json:"description"`
+	ParentID    *int   `json:"parent_id"` // changing it moves the category to the end of its new parent
+}
+
+// MoveCategoryRequest puts a category under ParentID (nil for the top level)
+// at Position among its new siblings. Without a position it goes last.
+type MoveCategoryRequest struct {
+	ParentID *int `json:"parent_id"`
+	Position *int `json:"position" binding:"omitempty,gte=0"`
+}
+
+// SubtreeIDsSQL selects the ID of the category bound to its single
+// placeholder and of every category below it
+const SubtreeIDsSQL = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id = ?
+	UNION ALL
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+) SELECT id FROM subtree`
+
+// Slugify makes a URL slug from a name, e.g. "Fresh Fruit & Veg" becomes
+// "fresh-fruit-veg"
+func Slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,139 @@
+package category
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type CategoryRepository interface {
+	Create(category *Category) error
+	FindAll() ([]Category, error)
+	FindByID(id int) (*Category, error)
+	FindBySlug(slug string) (*Category, error)
+	FindByRef(ref string) (*Category, error)
+	FindByIDs(ids []int) ([]Category, error)
+	FindChildren(parentID *int) ([]Category, error)
+	SubtreeIDs(id int) ([]int, error)
+	Update(id int, category *Category) error
+	Move(id int, parentID *int, siblingOrder []int) error
+	Delete(id int) error
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) Create(category *Category) error {
+	return r.db.Omit("Children").Create(category).Error
+}
+
+// FindAll returns every category, flat, in sibling order
+func (r *categoryRepository) FindAll() ([]Category, error) {
+	var categories []Category
+	err := r.db.Order("position, id").Find(&categories).Error
+	return categories, err
+}
+
+func (r *categoryRepository) FindByID(id int) (*Category, error) {
+	var category Category
+	err := r.db.First(&category, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) FindBySlug(slug string) (*Category, error) {
+	var category Category
+	err := r.db.Where("slug = ?", slug).First(&category).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindByRef finds a category by numeric ID, or by slug for anything else
+func (r *categoryRepository) FindByRef(ref string) (*Category, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return r.FindByID(id)
+	}
+	return r.FindBySlug(ref)
+}
+
+func (r *categoryRepository) FindByIDs(ids []int) ([]Category, error) {
+	var categories []Category
+	if len(ids) == 0 {
+		return categories, nil
+	}
+	err := r.db.Where("id IN ?", ids).Order("id").Find(&categories).Error
+	return categories, err
+}
+
+// FindChildren returns the categories directly under parentID, or the
+// top-level categories when it is nil, in sibling order
+func (r *categoryRepository) FindChildren(parentID *int) ([]Category, error) {
+	var categories []Category
+	query := r.db.Order("position, id")
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	err := query.Find(&categories).Error
+	return categories, err
+}
+
+// SubtreeIDs returns id and the IDs of all its descendants
+func (r *categoryRepository) SubtreeIDs(id int) ([]int, error) {
+	var ids []int
+	err := r.db.Raw(SubtreeIDsSQL, id).Scan(&ids).Error
+	return ids, err
+}
+
+// Update saves the name, slug and description; the parent and position
+// change through Move
+func (r *categoryRepository) Update(id int, category *Category) error {
+	result := r.db.Model(&Category{}).Where("id = ?", id).
+		Select("name", "slug", "description").
+		Updates(category)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Move sets the category's parent and renumbers the new siblings, the moved
+// category included, in siblingOrder
+func (r *categoryRepository) Move(id int, parentID *int, siblingOrder []int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Category{}).Where("id = ?", id).Update("parent_id", parentID).Error; err != nil {
+			return err
+		}
+		for position, siblingID := range siblingOrder {
+			err := tx.Model(&Category{}).Where("id = ?", siblingID).Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *categoryRepository) Delete(id int) error {
+	result := r.db.Delete(&Category{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package category
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrSlugTaken        = errors.New("category slug already exists")
+	ErrInvalidSlug      = errors.New("category slug must contain letters or digits")
+	// ErrInvalidParent is returned for a parent that does not exist or that
+	// would make the tree loop
+	ErrInvalidParent       = errors.New("invalid parent category")
+	ErrCategoryHasChildren = errors.New("category has subcategories")
+)
+
+type CategoryService interface {
+	CreateCategory(req CategoryRequest) (*Category, error)
+	GetCategoryTree() ([]Category, error)
+	GetCategory(ref string) (*Category, error)
+	UpdateCategory(id int, req CategoryRequest) (*Category, error)
+	MoveCategory(id int, req MoveCategoryRequest) (*Category, error)
+	DeleteCategory(id int) error
+}
+
+type categoryService struct {
+	repo CategoryRepository
+}
+
+func NewCategoryService(repo CategoryRepository) CategoryService {
+	return &categoryService{repo: repo}
+}
+
+func (s *categoryService) CreateCategory(req CategoryRequest) (*Category, error) {
+	slug, err := s.slugFor(0, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkParent(0, req.ParentID); err != nil {
+		return nil, err
+	}
+	siblings, err := s.repo.FindChildren(req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &Category{
+		ParentID:    req.ParentID,
+		Name:        strings.TrimSpace(req.Name),
+		Slug:        slug,
+		Description: req.Description,
+		Position:    len(siblings),
+	}
+	err = s.repo.Create(category)
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// GetCategoryTree returns the top-level categories with their descendants
+// nested under Children
+func (s *categoryService) GetCategoryTree() ([]Category, error) {
+	categories, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(categories, nil), nil
+}
+
+// GetCategory finds a category by ID or slug, with its descendants nested
+// under Children
+func (s *categoryService) GetCategory(ref string) (*Category, error) {
+	category, err := s.repo.FindByRef(ref)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	categories, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	category.Children = buildTree(categories, &category.ID)
+	return category, nil
+}
+
+func (s *categoryService) UpdateCategory(id int, req CategoryRequest) (*Category, error) {
+	category, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	slug, err := s.slugFor(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameParent(category.ParentID, req.ParentID) {
+		if _, err := s.MoveCategory(id, MoveCategoryRequest{ParentID: req.ParentID}); err != nil {
+			return nil, err
+		}
+	}
+
+	category.Name = strings.TrimSpace(req.Name)
+	category.Slug = slug
+	category.Description = req.Description
+	err = s.repo.Update(id, category)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+// MoveCategory reparents and reorders a category. Its descendants move with it.
+func (s *categoryService) MoveCategory(id int, req MoveCategoryRequest) (*Category, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	if err := s.checkParent(id, req.ParentID); err != nil {
+		return nil, err
+	}
+
+	siblings, err := s.repo.FindChildren(req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	order := make([]int, 0, len(siblings)+1)
+	for _, sibling := range siblings {
+		if sibling.ID != id {
+			order = append(order, sibling.ID)
+		}
+	}
+	position := len(order)
+	if req.Position != nil && *req.Position < position {
+		position = *req.Position
+	}
+	order = slices.Insert(order, position, id)
+
+	if err := s.repo.Move(id, req.ParentID, order); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+// DeleteCategory removes a category and its product assignments. Categories
+// with subcategories must be emptied or moved first.
+func (s *categoryService) DeleteCategory(id int) error {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return ErrCategoryNotFound
+	}
+	children, err := s.repo.FindChildren(&id)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return ErrCategoryHasChildren
+	}
+	return s.repo.Delete(id)
+}
+
+// slugFor returns the requested slug, or one made from the name, after
+// checking no other category uses it
+func (s *categoryService) slugFor(id int, req CategoryRequest) (string, error) {
+	slug := Slugify(req.Slug)
+	if slug == "" {
+		slug = Slugify(req.Name)
+	}
+	if slug == "" {
+		return "", ErrInvalidSlug
+	}
+	if existing, _ := s.repo.FindBySlug(slug); existing != nil && existing.ID != id {
+		return "", ErrSlugTaken
+	}
+	return slug, nil
+}
+
+// checkParent verifies that parentID exists and is not the category itself
+// or one of its descendants
+func (s *categoryService) checkParent(id int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, err := s.repo.FindByID(*parentID); err != nil {
+		return ErrInvalidParent
+	}
+	if id == 0 {
+		return nil
+	}
+	subtree, err := s.repo.SubtreeIDs(id)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(subtree, *parentID) {
+		return ErrInvalidParent
+	}
+	return nil
+}
+
+// buildTree nests the categories under parentID, keeping sibling order
+func buildTree(categories []Category, parentID *int) []Category {
+	tree := []Category{}
+	for _, category := range categories {
+		if sameParent(category.ParentID, parentID) {
+			category.Children = buildTree(categories, &category.ID)
+			tree = append(tree, category)
+		}
+	}
+	return tree
+}
+
+func sameParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -1,10 +1,13 @@
 package order
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"mini-ecommerce/internal/product"
+	"mini-ecommerce/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,7 +24,7 @@ func NewOrderHandler(service OrderService, productRepo product.ProductRepository
 	}
 }
 
-// CreateOrder creates a new order
+// CreateOrder creates a new order for the caller; admins may place one for any user
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	var req CreateOrderRequest
 
@@ -30,7 +33,23 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if claims.Type != "admin" {
+		req.UserID = claims.ID
+	} else if req.UserID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
 	order, err := h.service.CreateOrder(req, h.productRepo)
+	if errors.Is(err, ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -47,9 +66,8 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 		return
 	}
 
-	order, err := h.service.GetOrderByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	order, ok := h.authorizeOrder(c, id)
+	if !ok {
 		return
 	}
 
@@ -64,6 +82,11 @@ func (h *OrderHandler) GetUserOrders(c *gin.Context) {
 		return
 	}
 
+	if !middleware.IsOwnerOrAdmin(c, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to these orders"})
+		return
+	}
+
 	orders, err := h.service.GetUserOrders(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
@@ -78,9 +101,41 @@ func (h *OrderHandler) GetUserOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, orders)
 }
 
-// GetAllOrders retrieves all orders (admin only)
+// GetMyOrders retrieves the logged-in user's orders
+func (h *OrderHandler) GetMyOrders(c *gin.Context) {
+	orders, err := h.service.GetUserOrders(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		return
+	}
+
+	if len(orders) == 0 {
+		c.JSON(http.StatusOK, []Order{})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetAllOrders retrieves all orders (admin only). Cancelled orders are
+// included unless include_cancelled=false; purged orders only with
+// include_deleted=true.
 func (h *OrderHandler) GetAllOrders(c *gin.Context) {
-	orders, err := h.service.GetAllOrders()
+	includeCancelled, err := strconv.ParseBool(c.DefaultQuery("include_cancelled", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid include_cancelled value"})
+		return
+	}
+	includeDeleted, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid include_deleted value"})
+		return
+	}
+
+	orders, err := h.service.GetAllOrders(OrderFilter{
+		IncludeCancelled: includeCancelled,
+		IncludeDeleted:   includeDeleted,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
 		return
@@ -94,7 +149,7 @@ func (h *OrderHandler) GetAllOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, orders)
 }
 
-// UpdateOrderStatus updates order status (admin only)
+// UpdateOrderStatus moves an order to a new status (admin only)
 func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -102,19 +157,17 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	var req map[string]string
+	var req UpdateOrderStatusRequest
 	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
 		return
 	}
 
-	status, exists := req["status"]
-	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+	order, err := h.service.UpdateOrderStatus(id, req, ActorFromContext(c))
+	if errors.Is(err, ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
-
-	order, err := h.service.UpdateOrderStatus(id, status)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -123,7 +176,28 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, order)
 }
 
-// CancelOrder cancels a pending order
+// GetOrderHistory retrieves the status changes of an order
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	if _, ok := h.authorizeOrder(c, id); !ok {
+		return
+	}
+
+	history, err := h.service.GetOrderHistory(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// CancelOrder cancels an order, with an optional reason in the body
 func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -131,11 +205,66 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		return
 	}
 
-	err = h.service.CancelOrder(id)
+	if _, ok := h.authorizeOrder(c, id); !ok {
+		return
+	}
+
+	var req CancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	order, err := h.service.CancelOrder(id, req, ActorFromContext(c))
+	if errors.Is(err, ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order cancelled successfully",
+		"order":   order,
+	})
+}
+
+// PurgeOrder soft-deletes an order (admin only)
+func (h *OrderHandler) PurgeOrder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	if err := h.service.PurgeOrder(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order purged successfully"})
+}
+
+// ActorFromContext builds the Actor for the token set by AuthMiddleware
+func ActorFromContext(c *gin.Context) Actor {
+	return Actor{Type: c.GetString("tokenType"), ID: c.GetInt("userID")}
+}
+
+// authorizeOrder loads the order and checks the caller owns it or is an admin.
+// It writes the error response itself and returns false when access is denied.
+func (h *OrderHandler) authorizeOrder(c *gin.Context, id int) (*Order, bool) {
+	order, err := h.service.GetOrderByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return nil, false
+	}
+
+	if !middleware.IsOwnerOrAdmin(c, order.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this order"})
+		return nil, false
+	}
+
+	return order, true
 }
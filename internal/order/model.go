@@ -1,39 +1,186 @@
 package order
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"mini-ecommerce/pkg/money"
+)
 
 type Order struct {
-	ID         int       `json:"order_id" gorm:"primaryKey"`
-	UserID     int       `json:"user_id"`
-	ProductID  int       `json:"product_id"`
-	Quantity   int       `json:"quantity"`
-	TotalPrice float64   `json:"total_price"`
-	Status     string    `json:"status"` // pending, confirmed, delivered
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID               int            `json:"order_id" gorm:"primaryKey"`
+	UserID           int            `json:"user_id"`
+	Items            []OrderItem    `json:"items" gorm:"foreignKey:OrderID"`
+	Subtotal         money.Money    `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+	Discount         money.Money    `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	CouponCode       string         `json:"coupon_code,omitempty"`
+	ShippingRegion   string         `json:"shipping_region,omitempty"`
+	TaxTotal         money.Money    `json:"tax_total" gorm:"embedded;embeddedPrefix:tax_total_"`
+	Taxes            []OrderTax     `json:"taxes" gorm:"foreignKey:OrderID"` // tax breakdown by rule
+	ShippingMethodID int            `json:"shipping_method_id,omitempty"`
+	ShippingMethod   string         `json:"shipping_method,omitempty"` // method name when the order was placed
+	ShippingCost     money.Money    `json:"shipping_cost" gorm:"embedded;embeddedPrefix:shipping_cost_"`
+	TotalPrice       money.Money    `json:"total_price" gorm:"embedded;embeddedPrefix:total_price_"` // subtotal - discount + tax_total + shipping_cost
+	Status           string         `json:"status" gorm:"index"`                                     // see Status* constants
+	CancelReason     string         `json:"cancel_reason,omitempty"`
+	CancelledAt      *time.Time     `json:"cancelled_at,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"` // set when an admin purges the order
+}
+
+// Order statuses. An order starts as pending and moves through the lifecycle
+// along the edges in statusTransitions.
+const (
+	StatusPending   = "pending"
+	StatusPaid      = "paid"
+	StatusConfirmed = "confirmed"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+	StatusReturned  = "returned"
+	StatusRefunded  = "refunded"
+)
+
+// statusTransitions lists the statuses an order may move to from each status
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusConfirmed, StatusCancelled, StatusRefunded},
+	StatusConfirmed: {StatusShipped, StatusCancelled},
+	StatusShipped:   {StatusDelivered, StatusReturned},
+	StatusDelivered: {StatusReturned},
+	StatusCancelled: {StatusRefunded},
+	StatusReturned:  {StatusRefunded},
+	StatusRefunded:  {},
+}
+
+// IsValidStatus reports whether status is a known order status
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
 }
 
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderItem is a single product line within an order. Name, SKU and Price
+// are copied from the product, or the chosen variant, when the order is placed,
+// so later catalogue and price changes do not alter past orders.
 type OrderItem struct {
-	ID       int     `json:"id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity int     `json:"quantity"`
-	Total    float64 `json:"total"`
+	ID        int         `json:"id" gorm:"primaryKey"`
+	OrderID   int         `json:"order_id" gorm:"index"`
+	ProductID int         `json:"product_id" gorm:"index"`
+	VariantID int         `json:"variant_id,omitempty" gorm:"index"` // 0 for products without variants
+	SKU       string      `json:"sku,omitempty"`
+	Name      string      `json:"name"`
+	Price     money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Quantity  int         `json:"quantity"`
+	Total     money.Money `json:"total" gorm:"embedded;embeddedPrefix:total_"`       // price * quantity
+	Discount  money.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"` // share of the order discount
+	TaxAmount money.Money `json:"tax_amount" gorm:"embedded;embeddedPrefix:tax_amount_"`
 }
 
-type CreateOrderRequest struct {
-	UserID    int `json:"user_id" binding:"required,gt=0"`
+// OrderTax is the tax charged on an order under one tax rule. Name and Rate
+// are copied from the rule when the order is placed.
+type OrderTax struct {
+	ID        int         `json:"-" gorm:"primaryKey"`
+	OrderID   int         `json:"-" gorm:"index"`
+	TaxRuleID int         `json:"tax_rule_id"`
+	Name      string      `json:"name"`
+	Rate      float64     `json:"rate"`                                            // percent
+	Taxable   money.Money `json:"taxable" gorm:"embedded;embeddedPrefix:taxable_"` // line totals after discounts
+	Amount    money.Money `json:"amount" gorm:"embedded"`
+}
+
+type OrderItemRequest struct {
 	ProductID int `json:"product_id" binding:"required,gt=0"`
+	VariantID int `json:"variant_id" binding:"omitempty,gt=0"` // required for products with variants
 	Quantity  int `json:"quantity" binding:"required,gt=0"`
 }
 
-type OrderResponse struct {
-	ID         int       `json:"id"`
-	UserID     int       `json:"user_id"`
-	ProductID  int       `json:"product_id"`
-	Quantity   int       `json:"quantity"`
-	TotalPrice float64   `json:"total_price"`
-	Status     string    `json:"status"`
+// CreateOrderRequest accepts either an items array or, for older clients,
+// a single product_id/quantity pair which becomes a one-item order. UserID is
+// taken from the token for user tokens and only honoured for admin tokens.
+type CreateOrderRequest struct {
+	UserID     int                `json:"user_id" binding:"omitempty,gt=0"`
+	ProductID  int                `json:"product_id" binding:"omitempty,gt=0"`
+	Quantity   int                `json:"quantity" binding:"omitempty,gt=0"`
+	Items      []OrderItemRequest `json:"items" binding:"omitempty,dive"`
+	CouponCode string             `json:"coupon_code"`
+	// ShippingRegion picks the tax rules and shipping zone, e.g. "BD-DHAKA"
+	ShippingRegion   string `json:"shipping_region"`
+	ShippingMethodID int    `json:"shipping_method_id" binding:"omitempty,gt=0"`
+}
+
+// LineItems returns the requested lines, falling back to the single-product fields
+func (r CreateOrderRequest) LineItems() []OrderItemRequest {
+	if len(r.Items) > 0 {
+		return r.Items
+	}
+	if r.ProductID > 0 {
+		return []OrderItemRequest{{ProductID: r.ProductID, Quantity: r.Quantity}}
+	}
+	return nil
+}
+
+// OrderStatusHistory records a single status change of an order
+type OrderStatusHistory struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	OrderID    int       `json:"order_id" gorm:"index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorType  string    `json:"actor_type"` // user, admin, system
+	ActorID    int       `json:"actor_id"`
+	Reason     string    `json:"reason"`
 	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
+
+// Actor identifies who is changing an order
+type Actor struct {
+	Type string // user, admin, system
+	ID   int
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// OrderFilter narrows the orders returned to admins
+type OrderFilter struct {
+	IncludeCancelled bool
+	IncludeDeleted   bool
+}
+
+type OrderResponse struct {
+	ID             int         `json:"id"`
+	UserID         int         `json:"user_id"`
+	Items          []OrderItem `json:"items"`
+	Subtotal       money.Money `json:"subtotal"`
+	Discount       money.Money `json:"discount"`
+	CouponCode     string      `json:"coupon_code,omitempty"`
+	TaxTotal       money.Money `json:"tax_total"`
+	Taxes          []OrderTax  `json:"taxes"`
+	ShippingMethod string      `json:"shipping_method,omitempty"`
+	ShippingCost   money.Money `json:"shipping_cost"`
+	TotalPrice     money.Money `json:"total_price"`
+	Status         string      `json:"status"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
 }
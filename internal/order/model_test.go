@@ -0,0 +1,39 @@
+package order
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusPaid, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusPending, StatusShipped, false},
+		{StatusPaid, StatusConfirmed, true},
+		{StatusPaid, StatusCancelled, true},
+		{StatusPaid, StatusRefunded, true},
+		{StatusPaid, StatusPending, false},
+		{StatusConfirmed, StatusShipped, true},
+		{StatusConfirmed, StatusCancelled, true},
+		{StatusConfirmed, StatusDelivered, false},
+		{StatusShipped, StatusDelivered, true},
+		{StatusShipped, StatusReturned, true},
+		{StatusShipped, StatusCancelled, false},
+		{StatusDelivered, StatusReturned, true},
+		{StatusDelivered, StatusCancelled, false},
+		{StatusCancelled, StatusRefunded, true},
+		{StatusCancelled, StatusPending, false},
+		{StatusReturned, StatusRefunded, true},
+		{StatusRefunded, StatusPending, false},
+		{StatusRefunded, StatusRefunded, false},
+		{StatusPending, StatusPending, false},
+		{"unknown", StatusPaid, false},
+		{StatusPending, "unknown", false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
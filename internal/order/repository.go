@@ -1,13 +1,29 @@
 package order
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mini-ecommerce/internal/product"
+	"mini-ecommerce/internal/promotion"
+)
+
+// TxHook runs inside the transaction that creates an order, after the order
+// has been inserted. Returning an error rolls the whole order back.
+type TxHook func(tx *gorm.DB, order *Order) error
 
 type OrderRepository interface {
-	Create(order *Order) error
+	Create(order *Order, hooks ...TxHook) error
 	FindByID(id int) (*Order, error)
 	FindByUserID(userID int) ([]Order, error)
-	FindAll() ([]Order, error)
+	FindAll(filter OrderFilter) ([]Order, error)
 	Update(id int, order *Order) error
+	UpdateStatus(entry *OrderStatusHistory) error
+	FindHistory(orderID int) ([]OrderStatusHistory, error)
 	Delete(id int) error
 }
 
@@ -19,13 +35,38 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
-func (r *orderRepository) Create(order *Order) error {
-	return r.db.Create(order).Error
+// Create reserves stock, inserts the order together with its items and runs
+// hooks in a single transaction
+func (r *orderRepository) Create(order *Order, hooks ...TxHook) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := reserveStock(tx, order.Items); err != nil {
+			return err
+		}
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		entry := &OrderStatusHistory{
+			OrderID:   order.ID,
+			ToStatus:  order.Status,
+			ActorType: "user",
+			ActorID:   order.UserID,
+			Reason:    "order placed",
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		for _, hook := range hooks {
+			if err := hook(tx, order); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (r *orderRepository) FindByID(id int) (*Order, error) {
 	var order Order
-	err := r.db.First(&order, id).Error
+	err := r.db.Preload("Items").Preload("Taxes").First(&order, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -34,16 +75,23 @@ func (r *orderRepository) FindByID(id int) (*Order, error) {
 
 func (r *orderRepository) FindByUserID(userID int) ([]Order, error) {
 	var orders []Order
-	err := r.db.Where("user_id = ?", userID).Find(&orders).Error
+	err := r.db.Preload("Items").Preload("Taxes").Where("user_id = ?", userID).Find(&orders).Error
 	if err != nil {
 		return nil, err
 	}
 	return orders, nil
 }
 
-func (r *orderRepository) FindAll() ([]Order, error) {
+func (r *orderRepository) FindAll(filter OrderFilter) ([]Order, error) {
 	var orders []Order
-	err := r.db.Find(&orders).Error
+	query := r.db.Preload("Items").Preload("Taxes")
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if !filter.IncludeCancelled {
+		query = query.Where("status <> ?", StatusCancelled)
+	}
+	err := query.Order("id").Find(&orders).Error
 	if err != nil {
 		return nil, err
 	}
@@ -51,9 +99,144 @@ func (r *orderRepository) FindAll() ([]Order, error) {
 }
 
 func (r *orderRepository) Update(id int, order *Order) error {
-	return r.db.Model(&Order{}).Where("id = ?", id).Updates(order).Error
+	return r.db.Model(&Order{}).Where("id = ?", id).Omit("Items", "Taxes").Updates(order).Error
+}
+
+// UpdateStatus moves the order from entry.FromStatus to entry.ToStatus and
+// records the change. Cancelling also returns stock, gives back the coupon use
+// and stores the reason.
+func (r *orderRepository) UpdateStatus(entry *OrderStatusHistory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&order, entry.OrderID).Error
+		if err != nil {
+			return err
+		}
+		// Another request changed the status since the caller read it
+		if order.Status != entry.FromStatus {
+			return fmt.Errorf("%w: order is now %s", ErrInvalidTransition, order.Status)
+		}
+
+		updates := map[string]interface{}{"status": entry.ToStatus}
+		if entry.ToStatus == StatusCancelled {
+			if err := restoreStock(tx, order.Items); err != nil {
+				return err
+			}
+			if err := promotion.NewCouponRepository(tx).Release(order.ID); err != nil {
+				return err
+			}
+			updates["cancel_reason"] = entry.Reason
+			updates["cancelled_at"] = time.Now()
+		}
+		if err := tx.Model(&Order{}).Where("id = ?", order.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
 }
 
+func (r *orderRepository) FindHistory(orderID int) ([]OrderStatusHistory, error) {
+	var history []OrderStatusHistory
+	err := r.db.Where("order_id = ?", orderID).Order("created_at, id").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Delete soft-deletes the order; items and history are kept
 func (r *orderRepository) Delete(id int) error {
-	return r.db.Delete(&Order{}, id).Error
+	result := r.db.Delete(&Order{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// reserveStock takes the ordered quantities off each product's stock, or
+// the variant's for lines with one. Rows are locked products first, then
+// variants, each in ID order, so concurrent orders cannot oversell or deadlock.
+func reserveStock(tx *gorm.DB, items []OrderItem) error {
+	products, variants := stockQuantities(items)
+	for _, id := range sortedIDs(products) {
+		var prod product.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "name", "stock").First(&prod, id).Error
+		if err != nil {
+			return err
+		}
+		if prod.Stock < products[id] {
+			return fmt.Errorf("%w: %s has %d left", ErrInsufficientStock, prod.Name, prod.Stock)
+		}
+
+		err = tx.Model(&product.Product{}).Where("id = ?", id).
+			UpdateColumn("stock", gorm.Expr("stock - ?", products[id])).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range sortedIDs(variants) {
+		var variant product.Variant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "sku", "stock").First(&variant, id).Error
+		if err != nil {
+			return err
+		}
+		if variant.Stock < variants[id] {
+			return fmt.Errorf("%w: %s has %d left", ErrInsufficientStock, variant.SKU, variant.Stock)
+		}
+
+		err = tx.Model(&product.Variant{}).Where("id = ?", id).
+			UpdateColumn("stock", gorm.Expr("stock - ?", variants[id])).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreStock puts the ordered quantities back on each product's or
+// variant's stock, including products in the trash
+func restoreStock(tx *gorm.DB, items []OrderItem) error {
+	products, variants := stockQuantities(items)
+	for _, id := range sortedIDs(products) {
+		err := tx.Unscoped().Model(&product.Product{}).Where("id = ?", id).
+			UpdateColumn("stock", gorm.Expr("stock + ?", products[id])).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range sortedIDs(variants) {
+		err := tx.Model(&product.Variant{}).Where("id = ?", id).
+			UpdateColumn("stock", gorm.Expr("stock + ?", variants[id])).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stockQuantities totals the ordered quantities by product, for lines
+// without a variant, and by variant
+func stockQuantities(items []OrderItem) (products map[int]int, variants map[int]int) {
+	products = make(map[int]int)
+	variants = make(map[int]int)
+	for _, item := range items {
+		if item.VariantID > 0 {
+			variants[item.VariantID] += item.Quantity
+		} else {
+			products[item.ProductID] += item.Quantity
+		}
+	}
+	return products, variants
+}
+
+func sortedIDs(quantities map[int]int) []int {
+	ids := make([]int, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
 }
@@ -2,52 +2,222 @@ package order
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
 
 	"mini-ecommerce/internal/product"
+	"mini-ecommerce/internal/promotion"
+	"mini-ecommerce/internal/shipping"
+	"mini-ecommerce/internal/tax"
+	"mini-ecommerce/pkg/money"
 )
 
+// ErrInsufficientStock is returned when a product does not have enough stock
+// on hand to cover an order.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrInvalidTransition is returned when an order cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid status transition")
+
 type OrderService interface {
-	CreateOrder(req CreateOrderRequest, productRepo product.ProductRepository) (*Order, error)
+	CreateOrder(req CreateOrderRequest, productRepo product.ProductRepository, hooks ...TxHook) (*Order, error)
 	GetOrderByID(id int) (*Order, error)
 	GetUserOrders(userID int) ([]Order, error)
-	GetAllOrders() ([]Order, error)
-	UpdateOrderStatus(id int, status string) (*Order, error)
-	CancelOrder(id int) error
+	GetAllOrders(filter OrderFilter) ([]Order, error)
+	UpdateOrderStatus(id int, req UpdateOrderStatusRequest, actor Actor) (*Order, error)
+	GetOrderHistory(id int) ([]OrderStatusHistory, error)
+	CancelOrder(id int, req CancelOrderRequest, actor Actor) (*Order, error)
+	PurgeOrder(id int) error
+	SetRefunder(refunds Refunder)
 }
 
-type orderService struct {
-	repo OrderRepository
+// Refunder gives the customer back whatever is still captured for an order.
+// Orders that were not paid through the payment gateway are left alone.
+type Refunder interface {
+	RefundBalance(orderID int) error
 }
 
-func NewOrderService(repo OrderRepository) OrderService {
-	return &orderService{repo: repo}
+type orderService struct {
+	repo       OrderRepository
+	promotions promotion.PromotionService
+	taxes      tax.TaxService
+	shipping   shipping.ShippingService
+	sales      product.SalePricer
+	refunds    Refunder
 }
 
-func (s *orderService) CreateOrder(req CreateOrderRequest, productRepo product.ProductRepository) (*Order, error) {
-	// Verify product exists
-	prod, err := productRepo.FindByID(req.ProductID)
-	if err != nil {
-		return nil, errors.New("product not found")
+func NewOrderService(repo OrderRepository, promotions promotion.PromotionService, taxes tax.TaxService, shipping shipping.ShippingService, sales product.SalePricer) OrderService {
+	return &orderService{
+		repo:       repo,
+		promotions: promotions,
+		taxes:      taxes,
+		shipping:   shipping,
+		sales:      sales,
 	}
+}
 
-	// Calculate total price
-	totalPrice := prod.Price * float64(req.Quantity)
+// CreateOrder prices the requested lines, at the sale price for products on
+// sale, applies the coupon if one is given, adds tax and the chosen shipping
+// method for the shipping region and stores the order. Any hooks run in the
+// same transaction as the insert.
+func (s *orderService) CreateOrder(req CreateOrderRequest, productRepo product.ProductRepository, hooks ...TxHook) (*Order, error) {
+	lines := req.LineItems()
+	if len(lines) == 0 {
+		return nil, errors.New("order must contain at least one item")
+	}
 
 	order := &Order{
-		UserID:    req.UserID,
-		ProductID: req.ProductID,
-		Quantity:  req.Quantity,
-		TotalPrice: totalPrice,
-		Status:    "pending",
+		UserID:         req.UserID,
+		ShippingRegion: tax.NormalizeCode(req.ShippingRegion),
+		Status:         StatusPending,
 	}
 
-	err = s.repo.Create(order)
+	taxClasses := make([]string, 0, len(lines))
+	var weight float64
+	for i, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than 0")
+		}
+
+		// Verify product exists and customers can buy it
+		prod, err := productRepo.FindByID(line.ProductID)
+		if err != nil || !prod.IsPublished(time.Now()) {
+			return nil, fmt.Errorf("product %d not found", line.ProductID)
+		}
+
+		// All amounts on the order are in the first product's currency
+		if i == 0 {
+			zero := money.Zero(prod.Price.Currency)
+			order.Subtotal, order.Discount, order.TaxTotal, order.ShippingCost = zero, zero, zero, zero
+		}
+		if !prod.Price.SameCurrency(order.Subtotal) {
+			return nil, fmt.Errorf("%s is priced in %s, not %s", prod.Name, prod.Price.Currency, order.Subtotal.Currency)
+		}
+
+		if err := s.sales.ApplySales([]*product.Product{prod}); err != nil {
+			return nil, err
+		}
+		sel, err := prod.Select(line.VariantID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Calculate line total and add it to the order subtotal
+		lineTotal := sel.Price.Mul(line.Quantity)
+		order.Items = append(order.Items, OrderItem{
+			ProductID: prod.ID,
+			VariantID: line.VariantID,
+			SKU:       sel.SKU,
+			Name:      sel.Name,
+			Price:     sel.Price,
+			Quantity:  line.Quantity,
+			Total:     lineTotal,
+			Discount:  money.Zero(prod.Price.Currency),
+			TaxAmount: money.Zero(prod.Price.Currency),
+		})
+		order.Subtotal = order.Subtotal.Add(lineTotal)
+		taxClasses = append(taxClasses, prod.TaxClass)
+		weight += sel.Weight * float64(line.Quantity)
+	}
+
+	if req.CouponCode != "" {
+		quote, err := s.applyCoupon(order, req.CouponCode)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, func(tx *gorm.DB, order *Order) error {
+			return s.promotions.Redeem(tx, quote, order.UserID, order.ID)
+		})
+	}
+	if err := s.applyTax(order, taxClasses); err != nil {
+		return nil, err
+	}
+	if req.ShippingMethodID > 0 {
+		if err := s.applyShipping(order, req.ShippingMethodID, weight); err != nil {
+			return nil, err
+		}
+	}
+	order.TotalPrice = order.Subtotal.Sub(order.Discount).Add(order.TaxTotal).Add(order.ShippingCost)
+
+	err := s.repo.Create(order, hooks...)
 	if err != nil {
 		return nil, err
 	}
 	return order, nil
 }
 
+// applyCoupon quotes the coupon against the order lines and stores the
+// discount on the order and its items
+func (s *orderService) applyCoupon(order *Order, code string) (*promotion.Quote, error) {
+	lines := make([]promotion.Line, len(order.Items))
+	for i, item := range order.Items {
+		lines[i] = promotion.Line{ProductID: item.ProductID, Amount: item.Total}
+	}
+
+	quote, err := s.promotions.Quote(code, order.UserID, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range order.Items {
+		order.Items[i].Discount = quote.LineDiscounts[i]
+	}
+	order.Discount = quote.Discount
+	order.CouponCode = quote.Coupon.Code
+	return quote, nil
+}
+
+// applyTax taxes each item after its discount and stores the per-item tax
+// and the breakdown by rule on the order
+func (s *orderService) applyTax(order *Order, taxClasses []string) error {
+	lines := make([]tax.Line, len(order.Items))
+	for i, item := range order.Items {
+		lines[i] = tax.Line{TaxClass: taxClasses[i], Amount: item.Total.Sub(item.Discount)}
+	}
+
+	result, err := s.taxes.Calculate(order.ShippingRegion, lines)
+	if err != nil {
+		return err
+	}
+
+	for i := range order.Items {
+		order.Items[i].TaxAmount = result.LineTaxes[i]
+	}
+	for _, b := range result.Breakdown {
+		order.Taxes = append(order.Taxes, OrderTax{
+			TaxRuleID: b.Rule.ID,
+			Name:      b.Rule.Name,
+			Rate:      b.Rule.Rate,
+			Taxable:   b.Taxable,
+			Amount:    b.Amount,
+		})
+	}
+	order.TaxTotal = result.Total
+	return nil
+}
+
+// applyShipping prices the chosen method for the order's region, weight and
+// discounted subtotal
+func (s *orderService) applyShipping(order *Order, methodID int, weight float64) error {
+	if order.ShippingRegion == "" {
+		return errors.New("shipping_region is required to ship an order")
+	}
+
+	parcel := shipping.Parcel{Weight: weight, Subtotal: order.Subtotal.Sub(order.Discount)}
+	option, err := s.shipping.Price(methodID, order.ShippingRegion, parcel)
+	if err != nil {
+		return err
+	}
+
+	order.ShippingMethodID = option.MethodID
+	order.ShippingMethod = option.Name
+	order.ShippingCost = option.Cost
+	return nil
+}
+
 func (s *orderService) GetOrderByID(id int) (*Order, error) {
 	return s.repo.FindByID(id)
 }
@@ -56,39 +226,97 @@ func (s *orderService) GetUserOrders(userID int) ([]Order, error) {
 	return s.repo.FindByUserID(userID)
 }
 
-func (s *orderService) GetAllOrders() ([]Order, error) {
-	return s.repo.FindAll()
+func (s *orderService) GetAllOrders(filter OrderFilter) ([]Order, error) {
+	return s.repo.FindAll(filter)
 }
 
-func (s *orderService) UpdateOrderStatus(id int, status string) (*Order, error) {
+// UpdateOrderStatus moves an order to a new status if the lifecycle allows it
+// and records who made the change
+func (s *orderService) UpdateOrderStatus(id int, req UpdateOrderStatusRequest, actor Actor) (*Order, error) {
 	order, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, errors.New("order not found")
 	}
+	return s.changeStatus(order, req, actor)
+}
+
+// SetRefunder sets what refunds paid orders when they are cancelled. The
+// payment service needs the order service, so it is set once both exist.
+func (s *orderService) SetRefunder(refunds Refunder) {
+	s.refunds = refunds
+}
 
-	validStatuses := map[string]bool{"pending": true, "confirmed": true, "delivered": true}
-	if !validStatuses[status] {
+// changeStatus moves an order that was read in the given status. It fails if
+// the status has changed since. Cancelling a paid or confirmed order refunds
+// the payment first, and the order is only cancelled if the refund succeeds.
+func (s *orderService) changeStatus(order *Order, req UpdateOrderStatusRequest, actor Actor) (*Order, error) {
+	if !IsValidStatus(req.Status) {
 		return nil, errors.New("invalid status")
 	}
+	if !CanTransition(order.Status, req.Status) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, order.Status, req.Status)
+	}
+
+	refunded := false
+	if req.Status == StatusCancelled && (order.Status == StatusPaid || order.Status == StatusConfirmed) {
+		if s.refunds == nil {
+			return nil, errors.New("paid orders cannot be cancelled: refunds are not available")
+		}
+		if err := s.refunds.RefundBalance(order.ID); err != nil {
+			return nil, fmt.Errorf("refund failed, order not cancelled: %w", err)
+		}
+		refunded = true
+	}
 
-	order.Status = status
-	err = s.repo.Update(id, order)
+	err := s.repo.UpdateStatus(&OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   req.Status,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		Reason:     req.Reason,
+	})
 	if err != nil {
+		if refunded {
+			log.Printf("order %d: payment refunded but the order was not cancelled: %v", order.ID, err)
+		}
 		return nil, err
 	}
 
-	return s.repo.FindByID(id)
+	return s.repo.FindByID(order.ID)
 }
 
-func (s *orderService) CancelOrder(id int) error {
+func (s *orderService) GetOrderHistory(id int) ([]OrderStatusHistory, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, errors.New("order not found")
+	}
+	return s.repo.FindHistory(id)
+}
+
+// CancelOrder marks the order as cancelled and returns its stock. The order
+// is kept so it still shows up in history and reports. Customers can only
+// cancel pending orders: once paid, an admin cancels the order, which
+// refunds the payment.
+func (s *orderService) CancelOrder(id int, req CancelOrderRequest, actor Actor) (*Order, error) {
 	order, err := s.repo.FindByID(id)
 	if err != nil {
-		return errors.New("order not found")
+		return nil, errors.New("order not found")
 	}
-
-	if order.Status != "pending" {
-		return errors.New("only pending orders can be cancelled")
+	if actor.Type == "user" && order.Status != StatusPending {
+		return nil, fmt.Errorf("%w: only pending orders can be cancelled; contact us to cancel a %s order",
+			ErrInvalidTransition, order.Status)
 	}
+	return s.changeStatus(order, UpdateOrderStatusRequest{
+		Status: StatusCancelled,
+		Reason: req.Reason,
+	}, actor)
+}
 
-	return s.repo.Delete(id)
+// PurgeOrder hides an order from every listing. The row is soft-deleted and
+// can still be read with the include_deleted filter.
+func (s *orderService) PurgeOrder(id int) error {
+	if err := s.repo.Delete(id); err != nil {
+		return errors.New("order not found")
+	}
+	return nil
 }
@@ -0,0 +1,95 @@
+package order
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeOrderRepository holds a single order in memory
+type fakeOrderRepository struct {
+	OrderRepository
+	order *Order
+}
+
+func (r *fakeOrderRepository) FindByID(id int) (*Order, error) {
+	if r.order == nil || r.order.ID != id {
+		return nil, errors.New("record not found")
+	}
+	found := *r.order
+	return &found, nil
+}
+
+func (r *fakeOrderRepository) UpdateStatus(entry *OrderStatusHistory) error {
+	if r.order.Status != entry.FromStatus {
+		return ErrInvalidTransition
+	}
+	r.order.Status = entry.ToStatus
+	return nil
+}
+
+// fakeRefunder records the orders it refunds
+type fakeRefunder struct {
+	err      error
+	refunded []int
+}
+
+func (f *fakeRefunder) RefundBalance(orderID int) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.refunded = append(f.refunded, orderID)
+	return nil
+}
+
+func TestCancelOrderRefunds(t *testing.T) {
+	admin := Actor{Type: "admin", ID: 1}
+	customer := Actor{Type: "user", ID: 7}
+
+	tests := []struct {
+		name         string
+		status       string
+		actor        Actor
+		refundErr    error
+		wantStatus   string
+		wantRefunded bool
+		wantErr      bool
+	}{
+		{"admin cancels a paid order", StatusPaid, admin, nil, StatusCancelled, true, false},
+		{"admin cancels a confirmed order", StatusConfirmed, admin, nil, StatusCancelled, true, false},
+		{"admin cancels a pending order", StatusPending, admin, nil, StatusCancelled, false, false},
+		{"customer cancels a pending order", StatusPending, customer, nil, StatusCancelled, false, false},
+		{"customer cannot cancel a paid order", StatusPaid, customer, nil, StatusPaid, false, true},
+		{"failed refund keeps the order", StatusPaid, admin, errors.New("gateway down"), StatusPaid, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeOrderRepository{order: &Order{ID: 3, UserID: 7, Status: tt.status}}
+			refunds := &fakeRefunder{err: tt.refundErr}
+			service := NewOrderService(repo, nil, nil, nil, nil)
+			service.SetRefunder(refunds)
+
+			_, err := service.CancelOrder(3, CancelOrderRequest{Reason: "out of stock"}, tt.actor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CancelOrder() error = %v, want error %v", err, tt.wantErr)
+			}
+			if repo.order.Status != tt.wantStatus {
+				t.Errorf("order status = %s, want %s", repo.order.Status, tt.wantStatus)
+			}
+			if refunded := len(refunds.refunded) > 0; refunded != tt.wantRefunded {
+				t.Errorf("refunded = %v, want %v", refunded, tt.wantRefunded)
+			}
+		})
+	}
+}
+
+func TestCancelPaidOrderWithoutRefunder(t *testing.T) {
+	repo := &fakeOrderRepository{order: &Order{ID: 3, Status: StatusPaid}}
+	service := NewOrderService(repo, nil, nil, nil, nil)
+
+	if _, err := service.CancelOrder(3, CancelOrderRequest{}, Actor{Type: "admin", ID: 1}); err == nil {
+		t.Fatal("CancelOrder() of a paid order succeeded with no way to refund it")
+	}
+	if repo.order.Status != StatusPaid {
+		t.Errorf("order status = %s, want %s", repo.order.Status, StatusPaid)
+	}
+}
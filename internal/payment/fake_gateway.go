@@ -0,0 +1,129 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// FakeGateway is an in-process gateway for development and testing. Intents
+// live in memory and webhooks are signed with HMAC-SHA256 of the body.
+type FakeGateway struct {
+	secret []byte
+
+	mu      sync.Mutex
+	nextID  int
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	amount   money.Money
+	refunded money.Money
+	status   string
+}
+
+func NewFakeGateway(webhookSecret string) *FakeGateway {
+	return &FakeGateway{
+		secret:  []byte(webhookSecret),
+		intents: make(map[string]*fakeIntent),
+	}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) CreateIntent(amount money.Money, reference string) (*Intent, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.nextID++
+	id := fmt.Sprintf("fake_pi_%d", g.nextID)
+	g.intents[id] = &fakeIntent{amount: amount, refunded: money.Zero(amount.Currency), status: StatusPending}
+
+	return &Intent{ID: id, ClientSecret: id + "_secret"}, nil
+}
+
+func (g *FakeGateway) Capture(intentID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return fmt.Errorf("unknown intent %s", intentID)
+	}
+	if intent.status != StatusAuthorized {
+		return fmt.Errorf("intent %s is %s, not authorized", intentID, intent.status)
+	}
+	intent.status = StatusCaptured
+	return nil
+}
+
+func (g *FakeGateway) Refund(intentID string, amount money.Money) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return fmt.Errorf("unknown intent %s", intentID)
+	}
+	if intent.status != StatusCaptured {
+		return fmt.Errorf("intent %s is %s, not captured", intentID, intent.status)
+	}
+	if !amount.IsPositive() || !amount.SameCurrency(intent.amount) || intent.refunded.Add(amount).Cmp(intent.amount) > 0 {
+		return errors.New("refund amount exceeds captured amount")
+	}
+	intent.refunded = intent.refunded.Add(amount)
+	return nil
+}
+
+func (g *FakeGateway) VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, g.mac(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Authorize simulates the customer completing the payment with the provider.
+// It marks the intent authorized and returns the signed webhook the provider
+// would send.
+func (g *FakeGateway) Authorize(intentID string) (payload []byte, signature string, err error) {
+	g.mu.Lock()
+	intent, ok := g.intents[intentID]
+	if ok && intent.status == StatusPending {
+		intent.status = StatusAuthorized
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		return nil, "", fmt.Errorf("unknown intent %s", intentID)
+	}
+
+	payload, err = json.Marshal(WebhookEvent{Type: EventPaymentAuthorized, IntentID: intentID})
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, hex.EncodeToString(g.mac(payload)), nil
+}
+
+func (g *FakeGateway) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, g.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
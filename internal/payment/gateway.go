@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Webhook event types sent by gateways
+const (
+	EventPaymentAuthorized = "payment.authorized"
+	EventPaymentFailed     = "payment.failed"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Intent is a provider-side request to collect a payment
+type Intent struct {
+	ID           string
+	ClientSecret string // handed to the client to complete the payment with the provider
+}
+
+// WebhookEvent is a verified notification from a payment provider
+type WebhookEvent struct {
+	Type     string `json:"type"`
+	IntentID string `json:"intent_id"`
+}
+
+// Gateway is implemented by every payment provider
+type Gateway interface {
+	// Name identifies the provider on stored payments
+	Name() string
+	// CreateIntent starts collecting amount; reference is our own identifier for the payment
+	CreateIntent(amount money.Money, reference string) (*Intent, error)
+	// Capture collects the funds of an authorized intent
+	Capture(intentID string) error
+	// Refund returns amount of a captured intent to the customer
+	Refund(intentID string, amount money.Money) error
+	// VerifyWebhook checks the signature of a webhook body and decodes it
+	VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
+
+// NewGateway returns the gateway for the configured provider name. The fake
+// gateway is only returned when allowFake is set.
+func NewGateway(provider string, webhookSecret string, allowFake bool) (Gateway, error) {
+	switch provider {
+	case "fake":
+		if !allowFake {
+			return nil, errors.New("the fake payment provider is not enabled")
+		}
+		return NewFakeGateway(webhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", provider)
+	}
+}
@@ -0,0 +1,153 @@
+package payment
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SignatureHeader carries the provider's signature of the webhook body
+const SignatureHeader = "X-Webhook-Signature"
+
+type PaymentHandler struct {
+	service      PaymentService
+	orderService order.OrderService
+}
+
+func NewPaymentHandler(service PaymentService, orderService order.OrderService) *PaymentHandler {
+	return &PaymentHandler{
+		service:      service,
+		orderService: orderService,
+	}
+}
+
+// StartPayment creates a payment intent for one of the caller's pending orders
+func (h *PaymentHandler) StartPayment(c *gin.Context) {
+	var req StartPaymentRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ord, ok := h.authorizeOrder(c, req.OrderID)
+	if !ok {
+		return
+	}
+
+	payment, err := h.service.StartPayment(ord)
+	if errors.Is(err, ErrOrderNotPayable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, payment)
+}
+
+// GetOrderPayments lists the payments made for an order
+func (h *PaymentHandler) GetOrderPayments(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	if _, ok := h.authorizeOrder(c, orderID); !ok {
+		return
+	}
+
+	payments, err := h.service.GetOrderPayments(orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
+		return
+	}
+
+	if len(payments) == 0 {
+		c.JSON(http.StatusOK, []Payment{})
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+// Webhook receives notifications from the payment provider. It is not behind
+// AuthMiddleware; the body signature authenticates the caller instead.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err = h.service.HandleWebhook(payload, c.GetHeader(SignatureHeader))
+	switch {
+	case errors.Is(err, ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"received": true})
+	}
+}
+
+// FakeAuthorize completes a payment on the fake gateway as if the customer
+// had paid, delivering the signed webhook through the normal webhook path
+func (h *PaymentHandler) FakeAuthorize(gateway *FakeGateway) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payment, err := h.service.GetPaymentByIntentID(c.Param("intent_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			return
+		}
+
+		if _, ok := h.authorizeOrder(c, payment.OrderID); !ok {
+			return
+		}
+
+		payload, signature, err := gateway.Authorize(payment.IntentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := h.service.HandleWebhook(payload, signature); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		payment, err = h.service.GetPaymentByIntentID(payment.IntentID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, payment)
+	}
+}
+
+// authorizeOrder loads the order and checks the caller owns it or is an admin.
+// It writes the error response itself and returns false when access is denied.
+func (h *PaymentHandler) authorizeOrder(c *gin.Context, orderID int) (*order.Order, bool) {
+	ord, err := h.orderService.GetOrderByID(orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return nil, false
+	}
+
+	if !middleware.IsOwnerOrAdmin(c, ord.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this order"})
+		return nil, false
+	}
+
+	return ord, true
+}
@@ -0,0 +1,34 @@
+package payment
+
+import (
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Payment statuses
+const (
+	StatusPending    = "pending"    // intent created, waiting for the customer
+	StatusAuthorized = "authorized" // provider reported the customer paid, capture in progress
+	StatusCaptured   = "captured"
+	StatusFailed     = "failed"
+	StatusRefunded   = "refunded"
+)
+
+// Payment links a provider payment intent to an order
+type Payment struct {
+	ID             int         `json:"id" gorm:"primaryKey"`
+	OrderID        int         `json:"order_id" gorm:"index"`
+	Provider       string      `json:"provider"`
+	IntentID       string      `json:"intent_id" gorm:"uniqueIndex"`
+	ClientSecret   string      `json:"client_secret,omitempty"`
+	Amount         money.Money `json:"amount" gorm:"embedded"`
+	RefundedAmount money.Money `json:"refunded_amount" gorm:"embedded;embeddedPrefix:refunded_"`
+	Status         string      `json:"status" gorm:"index"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+type StartPaymentRequest struct {
+	OrderID int `json:"order_id" binding:"required,gt=0"`
+}
@@ -0,0 +1,88 @@
+package payment
+
+import (
+	"gorm.io/gorm"
+
+	"mini-ecommerce/pkg/money"
+)
+
+type PaymentRepository interface {
+	Create(payment *Payment) error
+	FindByIntentID(intentID string) (*Payment, error)
+	FindByOrderID(orderID int) ([]Payment, error)
+	FindOpenByOrderID(orderID int) (*Payment, error)
+	FindCapturedByOrderID(orderID int) (*Payment, error)
+	AddRefund(id int, amount money.Money) error
+	UpdateStatus(id int, from string, to string) (bool, error)
+}
+
+type paymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepository {
+	return &paymentRepository{db: db}
+}
+
+func (r *paymentRepository) Create(payment *Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *paymentRepository) FindByIntentID(intentID string) (*Payment, error) {
+	var payment Payment
+	err := r.db.Where("intent_id = ?", intentID).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) FindByOrderID(orderID int) ([]Payment, error) {
+	var payments []Payment
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// FindOpenByOrderID returns the order's payment that is still waiting for the customer
+func (r *paymentRepository) FindOpenByOrderID(orderID int) (*Payment, error) {
+	var payment Payment
+	err := r.db.Where("order_id = ? AND status = ?", orderID, StatusPending).Order("id DESC").First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// FindCapturedByOrderID returns the order's payment whose funds were collected
+func (r *paymentRepository) FindCapturedByOrderID(orderID int) (*Payment, error) {
+	var payment Payment
+	err := r.db.Where("order_id = ? AND status = ?", orderID, StatusCaptured).Order("id DESC").First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// AddRefund records a refunded amount and marks the payment refunded once
+// the whole amount has been returned
+func (r *paymentRepository) AddRefund(id int, amount money.Money) error {
+	return r.db.Model(&Payment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"refunded_amount":   gorm.Expr("refunded_amount + ?", amount.Amount),
+		"refunded_currency": amount.Currency,
+		"status":            gorm.Expr("CASE WHEN refunded_amount + ? >= amount THEN ? ELSE status END", amount.Amount, StatusRefunded),
+	}).Error
+}
+
+// UpdateStatus moves a payment from one status to another. It reports false
+// when the payment was no longer in the expected status, so duplicate
+// webhooks are applied only once.
+func (r *paymentRepository) UpdateStatus(id int, from string, to string) (bool, error) {
+	result := r.db.Model(&Payment{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/pkg/money"
+)
+
+var (
+	ErrOrderNotPayable = errors.New("only pending orders can be paid")
+	ErrPaymentNotFound = errors.New("payment not found")
+
+	// ErrNoCapturedPayment is returned when refunding an order that was never paid through the gateway
+	ErrNoCapturedPayment = errors.New("order has no captured payment")
+)
+
+type PaymentService interface {
+	StartPayment(ord *order.Order) (*Payment, error)
+	GetPaymentByIntentID(intentID string) (*Payment, error)
+	GetOrderPayments(orderID int) ([]Payment, error)
+	HandleWebhook(payload []byte, signature string) error
+	RefundOrder(orderID int, amount money.Money) (*Payment, error)
+	RefundBalance(orderID int) error
+}
+
+type paymentService struct {
+	repo         PaymentRepository
+	gateway      Gateway
+	orderService order.OrderService
+}
+
+func NewPaymentService(repo PaymentRepository, gateway Gateway, orderService order.OrderService) PaymentService {
+	return &paymentService{
+		repo:         repo,
+		gateway:      gateway,
+		orderService: orderService,
+	}
+}
+
+// StartPayment creates a payment intent for a pending order, or returns the
+// one already waiting for the customer
+func (s *paymentService) StartPayment(ord *order.Order) (*Payment, error) {
+	if ord.Status != order.StatusPending {
+		return nil, ErrOrderNotPayable
+	}
+
+	if existing, err := s.repo.FindOpenByOrderID(ord.ID); err == nil {
+		return existing, nil
+	}
+
+	intent, err := s.gateway.CreateIntent(ord.TotalPrice, fmt.Sprintf("order-%d", ord.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &Payment{
+		OrderID:        ord.ID,
+		Provider:       s.gateway.Name(),
+		IntentID:       intent.ID,
+		ClientSecret:   intent.ClientSecret,
+		Amount:         ord.TotalPrice,
+		RefundedAmount: money.Zero(ord.TotalPrice.Currency),
+		Status:         StatusPending,
+	}
+	if err := s.repo.Create(payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (s *paymentService) GetPaymentByIntentID(intentID string) (*Payment, error) {
+	payment, err := s.repo.FindByIntentID(intentID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	return payment, nil
+}
+
+func (s *paymentService) GetOrderPayments(orderID int) ([]Payment, error) {
+	return s.repo.FindByOrderID(orderID)
+}
+
+// HandleWebhook applies a provider notification. Authorized payments are
+// captured and their order is marked paid.
+func (s *paymentService) HandleWebhook(payload []byte, signature string) error {
+	event, err := s.gateway.VerifyWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	payment, err := s.repo.FindByIntentID(event.IntentID)
+	if err != nil {
+		return ErrPaymentNotFound
+	}
+
+	switch event.Type {
+	case EventPaymentAuthorized:
+		return s.capture(payment)
+	case EventPaymentFailed:
+		_, err := s.repo.UpdateStatus(payment.ID, StatusPending, StatusFailed)
+		return err
+	default:
+		// Events we do not act on are acknowledged so the provider stops retrying
+		return nil
+	}
+}
+
+// RefundOrder returns amount of the order's captured payment to the customer
+func (s *paymentService) RefundOrder(orderID int, amount money.Money) (*Payment, error) {
+	payment, err := s.repo.FindCapturedByOrderID(orderID)
+	if err != nil {
+		return nil, ErrNoCapturedPayment
+	}
+	if !amount.SameCurrency(payment.Amount) || amount.Cmp(payment.Amount.Sub(payment.RefundedAmount)) > 0 {
+		return nil, errors.New("refund amount exceeds the amount still captured")
+	}
+
+	if err := s.gateway.Refund(payment.IntentID, amount); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddRefund(payment.ID, amount); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByIntentID(payment.IntentID)
+}
+
+// RefundBalance refunds whatever of the order's captured payment has not
+// been refunded yet. Orders with no captured payment are left alone.
+func (s *paymentService) RefundBalance(orderID int) error {
+	payment, err := s.repo.FindCapturedByOrderID(orderID)
+	if err != nil {
+		return nil
+	}
+	balance := payment.Amount.Sub(payment.RefundedAmount)
+	if !balance.IsPositive() {
+		return nil
+	}
+	_, err = s.RefundOrder(orderID, balance)
+	return err
+}
+
+func (s *paymentService) capture(payment *Payment) error {
+	claimed, err := s.repo.UpdateStatus(payment.ID, StatusPending, StatusAuthorized)
+	if err != nil || !claimed {
+		return err
+	}
+
+	if err := s.gateway.Capture(payment.IntentID); err != nil {
+		if _, revertErr := s.repo.UpdateStatus(payment.ID, StatusAuthorized, StatusFailed); revertErr != nil {
+			log.Printf("payment %d: failed to mark failed: %v", payment.ID, revertErr)
+		}
+		return err
+	}
+	if _, err := s.repo.UpdateStatus(payment.ID, StatusAuthorized, StatusCaptured); err != nil {
+		return err
+	}
+
+	_, err = s.orderService.UpdateOrderStatus(payment.OrderID, order.UpdateOrderStatusRequest{
+		Status: order.StatusPaid,
+		Reason: fmt.Sprintf("payment %s captured", payment.IntentID),
+	}, order.Actor{Type: "system"})
+	if err != nil {
+		// The order moved on (e.g. was cancelled) while the customer was paying
+		log.Printf("payment %d: order %d not marked paid, refunding: %v", payment.ID, payment.OrderID, err)
+		if refundErr := s.gateway.Refund(payment.IntentID, payment.Amount); refundErr != nil {
+			return refundErr
+		}
+		return s.repo.AddRefund(payment.ID, payment.Amount)
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package product
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Bulk import and export file formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl" // JSON Lines: one product object per line
+)
+
+var (
+	// ErrInvalidImport is returned for import files that cannot be read at
+	// all, as opposed to single records that fail
+	ErrInvalidImport = errors.New("invalid import file")
+	// ErrInvalidRecord is returned for one record of an import file that
+	// cannot be decoded or fails validation
+	ErrInvalidRecord = errors.New("invalid record")
+)
+
+// maxJSONLLine caps the length of one JSON Lines record
+const maxJSONLLine = 1 << 20
+
+// csvColumns are the columns of bulk CSV files, in export order. category_ids
+// holds IDs separated by semicolons, e.g. "1;4", and the publishing times
+// are RFC 3339.
+var csvColumns = []string{
+	"sku", "name", "price", "currency", "weight", "colour",
+	"description", "stock", "tax_class", "category_ids",
+	"status", "publish_at", "unpublish_at",
+}
+
+// ImportReport sums up a bulk import. Errors lists the records that were
+// skipped, by the line of the file they start on.
+type ImportReport struct {
+	DryRun  bool       `json:"dry_run"` // nothing was saved
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+}
+
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// FormatForFilename picks the bulk format from a file extension, or returns
+// "" when it is not recognised
+func FormatForFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	default:
+		return ""
+	}
+}
+
+// productRecord is one product in an import file
+type productRecord struct {
+	CreateProductRequest
+	// HasStock is set when the record gives a stock level. Updates leave
+	// stock alone otherwise, as a missing value is not a stock of 0.
+	HasStock bool `json:"-"`
+}
+
+// recordReader reads products to import. Next returns the next record and
+// the line it starts on, or io.EOF after the last. Errors wrapping
+// ErrInvalidRecord affect only that record, and reading can go on.
+type recordReader interface {
+	Next() (*productRecord, int, error)
+}
+
+func newRecordReader(r io.Reader, format string) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVRecordReader(r)
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLine)
+		return &jsonlRecordReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("%w: format must be csv or jsonl", ErrInvalidImport)
+	}
+}
+
+type csvRecordReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// newCSVRecordReader reads the header row. Columns may come in any order,
+// but each must be one of csvColumns and name is required.
+func newCSVRecordReader(r io.Reader) (*csvRecordReader, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%w: the name column is required", ErrInvalidImport)
+	}
+	return &csvRecordReader{r: cr, columns: columns}, nil
+}
+
+func (cr *csvRecordReader) Next() (*productRecord, int, error) {
+	record, err := cr.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, parseErr.StartLine, fmt.Errorf("%w: %v", ErrInvalidRecord, parseErr.Err)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	line, _ := cr.r.FieldPos(0)
+
+	get := func(column string) string {
+		if i, ok := cr.columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	rec := &productRecord{CreateProductRequest: CreateProductRequest{
+		SKU:         get("sku"),
+		Name:        get("name"),
+		Colour:      get("colour"),
+		Description: get("description"),
+		TaxClass:    get("tax_class"),
+		Status:      strings.ToLower(get("status")),
+	}}
+	req := &rec.CreateProductRequest
+
+	if v := get("price"); v != "" {
+		currency := get("currency")
+		if currency == "" {
+			currency = money.DefaultCurrency()
+		}
+		if req.Price, err = money.Parse(v, currency); err != nil {
+			return nil, line, fmt.Errorf("%w: invalid price %q", ErrInvalidRecord, v)
+		}
+	}
+	if v := get("weight"); v != "" {
+		if req.Weight, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, line, fmt.Errorf("%w: invalid weight %q", ErrInvalidRecord, v)
+		}
+	}
+	if v := get("stock"); v != "" {
+		if req.Stock, err = strconv.Atoi(v); err != nil {
+			return nil, line, fmt.Errorf("%w: invalid stock %q", ErrInvalidRecord, v)
+		}
+		rec.HasStock = true
+	}
+	if v := get("category_ids"); v != "" {
+		for _, field := range strings.Split(v, ";") {
+			id, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return nil, line, fmt.Errorf("%w: invalid category_ids %q", ErrInvalidRecord, v)
+			}
+			req.CategoryIDs = append(req.CategoryIDs, id)
+		}
+	}
+	if req.PublishAt, err = parseTime("publish_at", get("publish_at")); err != nil {
+		return nil, line, err
+	}
+	if req.UnpublishAt, err = parseTime("unpublish_at", get("unpublish_at")); err != nil {
+		return nil, line, err
+	}
+	return rec, line, nil
+}
+
+// parseTime reads an optional RFC 3339 time from a CSV cell
+func parseTime(column string, v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s %q", ErrInvalidRecord, column, v)
+	}
+	return &t, nil
+}
+
+// jsonlRecordReader reads one CreateProductRequest object per line,
+// skipping blank lines
+type jsonlRecordReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (jr *jsonlRecordReader) Next() (*productRecord, int, error) {
+	for jr.scanner.Scan() {
+		jr.line++
+		text := bytes.TrimSpace(jr.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var rec productRecord
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			return nil, jr.line, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		// A null stock counts as missing
+		var stock struct {
+			Stock *int `json:"stock"`
+		}
+		if err := json.Unmarshal(text, &stock); err != nil {
+			return nil, jr.line, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		rec.HasStock = stock.Stock != nil
+		return &rec, jr.line, nil
+	}
+	if err := jr.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, jr.line + 1, fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidImport, jr.line+1, maxJSONLLine)
+		}
+		return nil, 0, err
+	}
+	return nil, 0, io.EOF
+}
+
+// recordWriter writes exported products. Flush must be called after the
+// last record.
+type recordWriter interface {
+	Write(record *CreateProductRequest) error
+	Flush() error
+}
+
+func newRecordWriter(w io.Writer, format string) (recordWriter, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return nil, err
+		}
+		return &csvRecordWriter{w: cw}, nil
+	case FormatJSONL:
+		return &jsonlRecordWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w: format must be csv or jsonl", ErrInvalidFilter)
+	}
+}
+
+type csvRecordWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvRecordWriter) Write(record *CreateProductRequest) error {
+	ids := make([]string, len(record.CategoryIDs))
+	for i, id := range record.CategoryIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	return cw.w.Write([]string{
+		record.SKU,
+		record.Name,
+		record.Price.Decimal(),
+		record.Price.Currency,
+		strconv.FormatFloat(record.Weight, 'f', -1, 64),
+		record.Colour,
+		record.Description,
+		strconv.Itoa(record.Stock),
+		record.TaxClass,
+		strings.Join(ids, ";"),
+		record.Status,
+		formatTime(record.PublishAt),
+		formatTime(record.UnpublishAt),
+	})
+}
+
+// formatTime writes an optional time for a CSV cell
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (cw *csvRecordWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonlRecordWriter struct {
+	enc *json.Encoder
+}
+
+func (jw *jsonlRecordWriter) Write(record *CreateProductRequest) error {
+	return jw.enc.Encode(record)
+}
+
+func (jw *jsonlRecordWriter) Flush() error {
+	return nil
+}
+
+// recordFromProduct is the export record for a product, which imports back
+// to the same product
+func recordFromProduct(p *Product) *CreateProductRequest {
+	ids := make([]int, len(p.Categories))
+	for i, c := range p.Categories {
+		ids[i] = c.ID
+	}
+	return &CreateProductRequest{
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Price:       p.Price,
+		Weight:      p.Weight,
+		Colour:      p.Colour,
+		Description: p.Description,
+		Stock:       p.Stock,
+		TaxClass:    p.TaxClass,
+		CategoryIDs: ids,
+		Status:      p.Status,
+		PublishAt:   p.PublishAt,
+		UnpublishAt: p.UnpublishAt,
+	}
+}
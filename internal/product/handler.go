@@ -1,8 +1,16 @@
 package product
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"time"
+
+	"mini-ecommerce/internal/category"
+	"mini-ecommerce/pkg/money"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,25 +23,140 @@ func NewProductHandler(service ProductService) *ProductHandler {
 	return &ProductHandler{service: service}
 }
 
-// GetAllProducts retrieves all products (accessible to customers)
+// GetAllProducts lists published products (accessible to customers). It
+// takes q, min_price, max_price, min_weight, max_weight, colour, category
+// (ID or slug), sort (price, name, created_at), order (asc, desc), limit and
+// offset.
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
-	products, err := h.service.GetAllProducts()
+	filter, err := parseProductFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.listProducts(c, filter)
+}
+
+// GetManagedProducts lists products in every status (admin only). It takes
+// the same filters as GetAllProducts, and status (draft, published,
+// archived).
+func (h *ProductHandler) GetManagedProducts(c *gin.Context) {
+	filter, err := parseProductFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.AllStatuses = true
+	filter.Status = c.Query("status")
+	h.listProducts(c, filter)
+}
+
+// GetDeletedProducts lists the products in the trash (admin only). It takes
+// the same filters as GetAllProducts.
+func (h *ProductHandler) GetDeletedProducts(c *gin.Context) {
+	filter, err := parseProductFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Deleted = true
+	h.listProducts(c, filter)
+}
+
+// GetCategoryProducts lists the products in a category and its
+// subcategories (accessible to customers). It takes the same filters as
+// GetAllProducts.
+func (h *ProductHandler) GetCategoryProducts(c *gin.Context) {
+	filter, err := parseProductFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Category = c.Param("id")
+	h.listProducts(c, filter)
+}
+
+func (h *ProductHandler) listProducts(c *gin.Context, filter ProductFilter) {
+	page, err := h.service.GetAllProducts(filter)
+	if errors.Is(err, ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, category.ErrCategoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
 		return
 	}
-	c.JSON(http.StatusOK, products)
+	c.JSON(http.StatusOK, page)
+}
+
+// SearchProducts ranks products by full-text relevance to q, with
+// highlighted matches (accessible to customers). It takes limit and offset.
+func (h *ProductHandler) SearchProducts(c *gin.Context) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.SearchProducts(c.Query("q"), limit, offset)
+	if errors.Is(err, ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search products"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
 }
 
-// GetProductByID retrieves a specific product
+// SuggestProducts autocompletes the storefront search box from the words
+// typed so far in q (accessible to customers). It takes limit.
+func (h *ProductHandler) SuggestProducts(c *gin.Context) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestions, err := h.service.SuggestProducts(c.Query("q"), limit)
+	if errors.Is(err, ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest products"})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// GetProductByID retrieves a specific published product
 func (h *ProductHandler) GetProductByID(c *gin.Context) {
+	h.getProduct(c, false)
+}
+
+// GetManagedProduct retrieves a product in any status (admin only)
+func (h *ProductHandler) GetManagedProduct(c *gin.Context) {
+	h.getProduct(c, true)
+}
+
+func (h *ProductHandler) getProduct(c *gin.Context, allStatuses bool) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
 		return
 	}
 
-	product, err := h.service.GetProductByID(id)
+	product, err := h.service.GetProductByID(id, allStatuses)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
@@ -51,7 +174,15 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.service.CreateProduct(req)
+	product, err := h.service.CreateProduct(req, c.GetInt("userID"))
+	if errors.Is(err, ErrInvalidPrice) || errors.Is(err, ErrInvalidCategory) || errors.Is(err, ErrInvalidSchedule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrSKUTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
 		return
@@ -60,7 +191,8 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	c.JSON(http.StatusCreated, product)
 }
 
-// UpdateProduct updates an existing product (admin only)
+// UpdateProduct replaces an existing product (admin only). The body is the
+// same as for CreateProduct, but stock and status are required.
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -68,13 +200,88 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	var req UpdateProductRequest
+	var req ReplaceProductRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	product, err := h.service.UpdateProduct(id, req, c.GetInt("userID"))
+	if err != nil {
+		respondUpdateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// PatchProduct changes the fields of a product given in a JSON Merge Patch
+// (admin only)
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req PatchProductRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	product, err := h.service.PatchProduct(id, req, c.GetInt("userID"))
+	if err != nil {
+		respondUpdateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// SetStatus publishes, unpublishes or archives a product, with optional
+// publish_at and unpublish_at times (admin only)
+func (h *ProductHandler) SetStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req SetStatusRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	product, err := h.service.SetStatus(id, req)
+	if errors.Is(err, ErrInvalidSchedule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// UpdateStock sets the stock on hand for a product (admin only)
+func (h *ProductHandler) UpdateStock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req UpdateStockRequest
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	product, err := h.service.UpdateProduct(id, req)
+	product, err := h.service.UpdateStock(id, req)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
@@ -83,7 +290,34 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, product)
 }
 
-// DeleteProduct deletes a product (admin only)
+// SetCategories replaces the categories a product is listed under (admin only)
+func (h *ProductHandler) SetCategories(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req SetCategoriesRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	product, err := h.service.SetCategories(id, req)
+	if errors.Is(err, ErrInvalidCategory) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// DeleteProduct moves a product to the trash (admin only)
 func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -99,3 +333,455 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
 }
+
+// RestoreProduct takes a product out of the trash (admin only)
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	product, err := h.service.RestoreProduct(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// PurgeProduct permanently deletes a product in the trash, unless an order
+// includes it (admin only)
+func (h *ProductHandler) PurgeProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	err = h.service.PurgeProduct(id)
+	if errors.Is(err, ErrProductInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product purged successfully"})
+}
+
+// CreateVariant adds a variant to a product (admin only)
+func (h *ProductHandler) CreateVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req VariantRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	variant, err := h.service.CreateVariant(id, req)
+	if err != nil {
+		respondVariantError(c, err, "Failed to create variant")
+		return
+	}
+	c.JSON(http.StatusCreated, variant)
+}
+
+// GenerateVariants creates the variant matrix from attribute options (admin only)
+func (h *ProductHandler) GenerateVariants(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req GenerateVariantsRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	variants, err := h.service.GenerateVariants(id, req)
+	if err != nil {
+		respondVariantError(c, err, "Failed to generate variants")
+		return
+	}
+	c.JSON(http.StatusCreated, variants)
+}
+
+// UpdateVariant replaces a variant of a product (admin only)
+func (h *ProductHandler) UpdateVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.Param("variant_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	var req VariantRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	variant, err := h.service.UpdateVariant(id, variantID, req)
+	if err != nil {
+		respondVariantError(c, err, "Failed to update variant")
+		return
+	}
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteVariant deletes a variant of a product (admin only)
+func (h *ProductHandler) DeleteVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.Param("variant_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	if err := h.service.DeleteVariant(id, variantID); err != nil {
+		respondVariantError(c, err, "Failed to delete variant")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+}
+
+// MaxImportBytes caps the size of an import file
+const MaxImportBytes = 50 << 20
+
+// importContentTypes maps request content types to bulk formats
+var importContentTypes = map[string]string{
+	"text/csv":                FormatCSV,
+	"application/x-ndjson":    FormatJSONL,
+	"application/jsonl":       FormatJSONL,
+	"application/x-jsonlines": FormatJSONL,
+}
+
+// exportContentTypes are the response content types of each bulk format
+var exportContentTypes = map[string]string{
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatJSONL: "application/x-ndjson",
+}
+
+// ImportProducts creates or updates products from a CSV or JSON Lines file
+// (admin only). The file is the request body, or the "file" field of a
+// multipart form. The format comes from ?format=, else the file name or
+// content type. ?dry_run=true validates without saving.
+func (h *ProductHandler) ImportProducts(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes)
+	format := c.Query("format")
+
+	var file io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload"})
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload"})
+			return
+		}
+		defer f.Close()
+		file = f
+		if format == "" {
+			format = FormatForFilename(header.Filename)
+		}
+	}
+	if format == "" {
+		format = importContentTypes[c.ContentType()]
+	}
+
+	report, err := h.service.ImportProducts(file, format, c.Query("dry_run") == "true", c.GetInt("userID"))
+	if errors.Is(err, ErrInvalidImport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import products"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportProducts streams the whole catalogue as CSV or JSON Lines, chosen
+// with ?format= (admin only)
+func (h *ProductHandler) ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or jsonl"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="products.`+format+`"`)
+	c.Status(http.StatusOK)
+	if err := h.service.ExportProducts(c.Writer, format); err != nil {
+		// The response has started, so the error can only be logged
+		_ = c.Error(err)
+	}
+}
+
+// GetPriceHistory lists a product's prices with the time each took effect
+// and the admin who set it (admin only). With ?at= (RFC 3339) it returns
+// just the price in effect at that time.
+func (h *ProductHandler) GetPriceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if v := c.Query("at"); v != "" {
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 time"})
+			return
+		}
+		change, err := h.service.GetPriceAt(id, at)
+		if err != nil {
+			respondPriceError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, change)
+		return
+	}
+
+	history, err := h.service.GetPriceHistory(id)
+	if err != nil {
+		respondPriceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// UploadImages adds images to a product's gallery (admin only). It takes a
+// multipart form with one or more "image" files and optional "alt_text"
+// values, matched to the files in order.
+func (h *ProductHandler) UploadImages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImagesPerUpload*MaxImageBytes+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload"})
+		return
+	}
+	files := form.File["image"]
+	if len(files) > MaxImagesPerUpload {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d images per upload", MaxImagesPerUpload)})
+		return
+	}
+
+	uploads := make([]ImageUpload, len(files))
+	for i, file := range files {
+		if file.Size > MaxImageBytes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is larger than %d MB", file.Filename, MaxImageBytes>>20)})
+			return
+		}
+		if uploads[i].Data, err = readFile(file); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload"})
+			return
+		}
+		if altTexts := form.Value["alt_text"]; i < len(altTexts) {
+			uploads[i].AltText = altTexts[i]
+		}
+	}
+
+	images, err := h.service.AddImages(id, uploads)
+	if err != nil {
+		respondImageError(c, err, "Failed to upload images")
+		return
+	}
+	c.JSON(http.StatusCreated, images)
+}
+
+// ReorderImages sets the order of a product's gallery (admin only)
+func (h *ProductHandler) ReorderImages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req ReorderImagesRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	images, err := h.service.ReorderImages(id, req)
+	if err != nil {
+		respondImageError(c, err, "Failed to reorder images")
+		return
+	}
+	c.JSON(http.StatusOK, images)
+}
+
+// DeleteImage removes an image from a product's gallery (admin only)
+func (h *ProductHandler) DeleteImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	imageID, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	if err := h.service.DeleteImage(id, imageID); err != nil {
+		respondImageError(c, err, "Failed to delete image")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
+}
+
+// respondPriceError maps price history errors to status codes
+func respondPriceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case errors.Is(err, ErrPriceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+	}
+}
+
+// respondUpdateError maps errors from replacing or patching a product to
+// status codes
+func respondUpdateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPatch), errors.Is(err, ErrInvalidPrice),
+		errors.Is(err, ErrInvalidCategory), errors.Is(err, ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSKUTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	}
+}
+
+// respondImageError maps image errors to status codes
+func respondImageError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case errors.Is(err, ErrImageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+	case errors.Is(err, ErrInvalidImage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// readFile reads an uploaded file into memory
+func readFile(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// respondVariantError maps variant errors to status codes
+func respondVariantError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case errors.Is(err, ErrVariantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+	case errors.Is(err, ErrSKUTaken), errors.Is(err, ErrVariantInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidVariant), errors.Is(err, ErrInvalidPrice):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// parseProductFilter reads the listing query parameters
+func parseProductFilter(c *gin.Context) (ProductFilter, error) {
+	filter := ProductFilter{
+		Query:    c.Query("q"),
+		Colour:   c.Query("colour"),
+		Category: c.Query("category"),
+		Sort:     c.Query("sort"),
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, fmt.Errorf("%w: order must be asc or desc", ErrInvalidFilter)
+	}
+
+	for name, dst := range map[string]**money.Money{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		if v := c.Query(name); v != "" {
+			price, err := money.Parse(v, money.DefaultCurrency())
+			if err != nil {
+				return filter, fmt.Errorf("%w: invalid %s", ErrInvalidFilter, name)
+			}
+			*dst = &price
+		}
+	}
+	for name, dst := range map[string]**float64{"min_weight": &filter.MinWeight, "max_weight": &filter.MaxWeight} {
+		if v := c.Query(name); v != "" {
+			weight, err := strconv.ParseFloat(v, 64)
+			if err != nil || weight < 0 {
+				return filter, fmt.Errorf("%w: invalid %s", ErrInvalidFilter, name)
+			}
+			*dst = &weight
+		}
+	}
+	var err error
+	if filter.Limit, err = queryInt(c, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// queryInt reads an optional integer query parameter, 0 when absent
+func queryInt(c *gin.Context, name string) (int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid %s", ErrInvalidFilter, name)
+	}
+	return n, nil
+}
@@ -1,30 +1,466 @@
 package product
 
-import "time"
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mini-ecommerce/internal/category"
+	"mini-ecommerce/pkg/imaging"
+	"mini-ecommerce/pkg/money"
+	"mini-ecommerce/pkg/patch"
+)
+
+// DefaultTaxClass is used for products created without a tax class
+const DefaultTaxClass = "STANDARD"
+
+// Listing defaults and limits for GET /products
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Sort keys accepted by the product listing
+const (
+	SortCreatedAt = "created_at"
+	SortName      = "name"
+	SortPrice     = "price"
+)
+
+// Product statuses. Customers only see published products, and only between
+// their publish and unpublish times.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived" // no longer sold, kept for the record
+)
+
+// Autocomplete defaults and limits for GET /products/suggest
+const (
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 25
+)
+
+// Limits on product image uploads
+const (
+	MaxImageBytes      = 10 << 20 // per file
+	MaxImagePixels     = 40_000_000
+	MaxImagesPerUpload = 10
+	MaxProductImages   = 30
+)
+
+// ThumbnailSize is a thumbnail made for every product image, fitting within
+// a square of Pixels
+type ThumbnailSize struct {
+	Name   string
+	Pixels int
+}
+
+// ThumbnailSizes are the thumbnails made for every product image
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", Pixels: 150},
+	{Name: "medium", Pixels: 400},
+	{Name: "large", Pixels: 800},
+}
 
 type Product struct {
+	ID          int         `json:"id" gorm:"primaryKey"`
+	Name        string      `json:"name" gorm:"index"`
+	SKU         string      `json:"sku" gorm:"size:100;not null;default:'';uniqueIndex:idx_products_sku,where:sku <> ''"` // optional; products with variants use theirs
+	Price       money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Weight      float64     `json:"weight"` // in kg
+	Colour      string      `json:"colour"`
+	Description string      `json:"description"`
+	Stock       int         `json:"stock" gorm:"not null;default:0"`              // units on hand
+	TaxClass    string      `json:"tax_class" gorm:"not null;default:'STANDARD'"` // matched against tax rules
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	// DeletedAt is set while the product is in the trash. Deleted products
+	// are hidden everywhere but the trash and stay referenced by past orders.
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Status is draft, published or archived. New products start as drafts;
+	// the column default keeps products from before statuses published.
+	Status      string     `json:"status" gorm:"size:20;not null;default:'published';index"`
+	PublishAt   *time.Time `json:"publish_at"`   // a published product is shown from then; nil for at once
+	UnpublishAt *time.Time `json:"unpublish_at"` // and until then; nil for no end
+
+	// RatingAverage and RatingCount summarise the approved reviews. They are
+	// kept up to date by the review service.
+	RatingAverage float64 `json:"rating_average" gorm:"not null;default:0"`
+	RatingCount   int     `json:"rating_count" gorm:"not null;default:0"`
+
+	Categories []category.Category `json:"categories,omitempty" gorm:"many2many:product_categories;constraint:OnDelete:CASCADE"`
+	Variants   []Variant           `json:"variants,omitempty" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"`
+	Images     []Image             `json:"images,omitempty" gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"` // gallery, in position order
+
+	// Sale is the product's price in a sale running now, filled in by the
+	// SalePricer; nil when it is not on sale
+	Sale *SalePrice `json:"sale,omitempty" gorm:"-"`
+
+	// SearchVector indexes name and description for full-text search. The
+	// repository sets it with SQL after every create or update.
+	SearchVector string `json:"-" gorm:"type:tsvector;index:idx_products_search,type:gin;->:false;<-:false"`
+}
+
+// Variant is one purchasable version of a product, such as the red shirt in
+// size M. A product with variants is sold only through them, and each
+// variant keeps its own stock.
+type Variant struct {
+	ID             int               `json:"id" gorm:"primaryKey"`
+	ProductID      int               `json:"product_id" gorm:"not null;uniqueIndex:idx_product_variants_attributes"`
+	SKU            string            `json:"sku" gorm:"uniqueIndex;not null"`
+	Attributes     map[string]string `json:"attributes" gorm:"serializer:json;type:jsonb"` // e.g. {"colour": "red", "size": "M"}
+	AttributeKey   string            `json:"-" gorm:"not null;uniqueIndex:idx_product_variants_attributes"`
+	PriceOverride  money.Money       `json:"price_override" gorm:"embedded;embeddedPrefix:price_override_"` // zero uses the product price
+	WeightOverride float64           `json:"weight_override"`                                               // zero uses the product weight
+	Stock          int               `json:"stock" gorm:"not null;default:0"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+
+	// Price and Weight are what the variant sells at and ships as, after
+	// falling back to the product's
+	Price  money.Money `json:"price" gorm:"-"`
+	Weight float64     `json:"weight" gorm:"-"`
+
+	// Sale is the variant's price in a sale running now, as for Product.Sale
+	Sale *SalePrice `json:"sale,omitempty" gorm:"-"`
+}
+
+func (Variant) TableName() string {
+	return "product_variants"
+}
+
+// Label describes the variant's attributes, e.g. "M, red"
+func (v *Variant) Label() string {
+	names := slices.Sorted(maps.Keys(v.Attributes))
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = v.Attributes[name]
+	}
+	return strings.Join(values, ", ")
+}
+
+// SalePrice is what a product or variant sells for during a sale
+type SalePrice struct {
+	SaleID int         `json:"sale_id"`
+	Name   string      `json:"name"`
+	Price  money.Money `json:"price"`
+	EndsAt time.Time   `json:"ends_at"`
+}
+
+// SalePricer fills in the Sale of products, and of their variants, that are
+// on sale now. The sale package implements it.
+type SalePricer interface {
+	ApplySales(products []*Product) error
+}
+
+// Selection is what a customer buys when ordering a product, or one of its
+// variants
+type Selection struct {
+	Product *Product
+	Variant *Variant // nil for products without variants
+	Name    string   // product name, with the variant's attributes
+	SKU     string
+	Price   money.Money
+	Weight  float64
+}
+
+// Select picks the variant to buy; variantID is 0 for products without
+// variants. The price is the sale price if a sale has been applied.
+func (p *Product) Select(variantID int) (*Selection, error) {
+	sel := &Selection{Product: p, Name: p.Name, SKU: p.SKU, Price: p.Price, Weight: p.Weight}
+	if p.Sale != nil {
+		sel.Price = p.Sale.Price
+	}
+	if variantID == 0 {
+		if len(p.Variants) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrVariantRequired, p.Name)
+		}
+		return sel, nil
+	}
+
+	for i := range p.Variants {
+		if v := &p.Variants[i]; v.ID == variantID {
+			sel.Variant = v
+			sel.Name = fmt.Sprintf("%s (%s)", p.Name, v.Label())
+			sel.SKU = v.SKU
+			sel.Price = v.Price
+			if v.Sale != nil {
+				sel.Price = v.Sale.Price
+			}
+			sel.Weight = v.Weight
+			return sel, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d is not a variant of %s", ErrVariantNotFound, variantID, p.Name)
+}
+
+// IsPublished reports whether customers can see and buy the product at the
+// given time
+func (p *Product) IsPublished(at time.Time) bool {
+	return p.Status == StatusPublished &&
+		(p.PublishAt == nil || !p.PublishAt.After(at)) &&
+		(p.UnpublishAt == nil || p.UnpublishAt.After(at))
+}
+
+// resolveVariants fills in each variant's price and weight
+func (p *Product) resolveVariants() {
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		v.Price = p.Price
+		if !v.PriceOverride.IsZero() {
+			v.Price = v.PriceOverride
+		}
+		v.Weight = p.Weight
+		if v.WeightOverride > 0 {
+			v.Weight = v.WeightOverride
+		}
+	}
+}
+
+// Image is a picture in a product's gallery. The original and its
+// thumbnails are kept in storage; only their keys are saved here.
+type Image struct {
 	ID          int       `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"index"`
-	Price       float64   `json:"price"`
-	Weight      float64   `json:"weight"` // in kg
-	Colour      string    `json:"colour"`
-	Description string    `json:"description"`
+	ProductID   int       `json:"product_id" gorm:"not null;index"`
+	Position    int       `json:"position" gorm:"not null;default:0"` // in the gallery, from 0
+	Key         string    `json:"-" gorm:"not null"`                  // storage key of the original
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	AltText     string    `json:"alt_text"`
 	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+
+	// URL and Thumbnails (by size name) are filled in from storage
+	URL        string            `json:"url" gorm:"-"`
+	Thumbnails map[string]string `json:"thumbnails" gorm:"-"`
+}
+
+func (Image) TableName() string {
+	return "product_images"
 }
 
+// ThumbnailKey is the storage key of the named thumbnail, e.g.
+// "products/12/4f9c0e_small.jpg" for "products/12/4f9c0e.png". PNG and GIF
+// originals get PNG thumbnails and the rest get JPEG.
+func (img *Image) ThumbnailKey(size string) string {
+	base := strings.TrimSuffix(img.Key, path.Ext(img.Key))
+	return base + "_" + size + imaging.Extension(imaging.ThumbnailType(img.ContentType))
+}
+
+// StorageKeys lists the keys of the original and all its thumbnails
+func (img *Image) StorageKeys() []string {
+	keys := []string{img.Key}
+	for _, size := range ThumbnailSizes {
+		keys = append(keys, img.ThumbnailKey(size.Name))
+	}
+	return keys
+}
+
+// ImageUpload is an uploaded image file, read by the handler
+type ImageUpload struct {
+	Data    []byte
+	AltText string
+}
+
+// ReorderImagesRequest lists every image of a product in its new gallery order
+type ReorderImagesRequest struct {
+	ImageIDs []int `json:"image_ids" binding:"required"`
+}
+
+// PriceChange is one period of a product's price history, recorded whenever
+// the price is set. The current price has no EffectiveTo. Orders keep their
+// own copy of the price paid, so this is for answering what a product cost
+// at a given time.
+type PriceChange struct {
+	ID            int         `json:"id" gorm:"primaryKey"`
+	ProductID     int         `json:"product_id" gorm:"not null;index:idx_product_price_history_product,priority:1"`
+	Price         money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	EffectiveFrom time.Time   `json:"effective_from" gorm:"not null;index:idx_product_price_history_product,priority:2"`
+	EffectiveTo   *time.Time  `json:"effective_to"`
+	ChangedBy     int         `json:"changed_by" gorm:"not null;default:0"` // admin ID; 0 for command-line imports and prices from before history was kept
+}
+
+func (PriceChange) TableName() string {
+	return "product_price_history"
+}
+
+// VariantRequest describes one variant. Leaving out price or weight uses the
+// product's.
+type VariantRequest struct {
+	SKU        string            `json:"sku" binding:"required"`
+	Attributes map[string]string `json:"attributes" binding:"required,min=1"`
+	Price      *money.Money      `json:"price"`
+	Weight     float64           `json:"weight" binding:"gte=0"`
+	Stock      int               `json:"stock" binding:"gte=0"`
+}
+
+// GenerateVariantsRequest creates a variant for every combination of the
+// option values, e.g. {"colour": ["red", "blue"], "size": ["S", "M"]} makes
+// four. Combinations the product already has are skipped. SKUs are the
+// prefix (by default made from the product name) followed by the values.
+type GenerateVariantsRequest struct {
+	Options   map[string][]string `json:"options" binding:"required,min=1"`
+	SKUPrefix string              `json:"sku_prefix"`
+	Price     *money.Money        `json:"price"`
+	Weight    float64             `json:"weight" binding:"gte=0"`
+	Stock     int                 `json:"stock" binding:"gte=0"`
+}
+
+// SearchVectorSQL builds a product's search document, weighting the name
+// above the description
+const SearchVectorSQL = "setweight(to_tsvector('english', coalesce(name, '')), 'A') || " +
+	"setweight(to_tsvector('english', coalesce(description, '')), 'B')"
+
+// CreateProductRequest creates a product
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	Weight      float64 `json:"weight" binding:"required,gt=0"`
-	Colour      string  `json:"colour" binding:"required"`
-	Description string  `json:"description" binding:"required"`
-}
-
-type UpdateProductRequest struct {
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Weight      float64 `json:"weight"`
-	Colour      string  `json:"colour"`
-	Description string  `json:"description"`
+	SKU         string      `json:"sku"`
+	Name        string      `json:"name" binding:"required"`
+	Price       money.Money `json:"price"` // must be positive
+	Weight      float64     `json:"weight" binding:"required,gt=0"`
+	Colour      string      `json:"colour"`
+	Description string      `json:"description"`
+	Stock       int         `json:"stock" binding:"gte=0"`
+	TaxClass    string      `json:"tax_class"` // defaults to STANDARD
+	CategoryIDs []int       `json:"category_ids"`
+	Status      string      `json:"status" binding:"omitempty,oneof=draft published archived"` // defaults to draft
+	PublishAt   *time.Time  `json:"publish_at"`
+	UnpublishAt *time.Time  `json:"unpublish_at"`
+}
+
+// ReplaceProductRequest replaces every field of a product with PUT. Stock and
+// status must be given; optional fields left out are cleared, including the
+// publishing schedule.
+type ReplaceProductRequest struct {
+	SKU         string      `json:"sku"`
+	Name        string      `json:"name" binding:"required"`
+	Price       money.Money `json:"price"` // must be positive
+	Weight      float64     `json:"weight" binding:"required,gt=0"`
+	Colour      string      `json:"colour"`
+	Description string      `json:"description"`
+	Stock       *int        `json:"stock" binding:"required,gte=0"`
+	TaxClass    string      `json:"tax_class"` // defaults to STANDARD
+	CategoryIDs []int       `json:"category_ids"`
+	Status      string      `json:"status" binding:"required,oneof=draft published archived"`
+	PublishAt   *time.Time  `json:"publish_at"`
+	UnpublishAt *time.Time  `json:"unpublish_at"`
+}
+
+// createRequest describes the replacement as the request that would create it
+func (r ReplaceProductRequest) createRequest() CreateProductRequest {
+	return CreateProductRequest{
+		SKU:         r.SKU,
+		Name:        r.Name,
+		Price:       r.Price,
+		Weight:      r.Weight,
+		Colour:      r.Colour,
+		Description: r.Description,
+		Stock:       *r.Stock,
+		TaxClass:    r.TaxClass,
+		CategoryIDs: r.CategoryIDs,
+		Status:      r.Status,
+		PublishAt:   r.PublishAt,
+		UnpublishAt: r.UnpublishAt,
+	}
+}
+
+// PatchProductRequest is a JSON Merge Patch of a product. Absent fields are
+// unchanged and null ones cleared; name, price, weight and status cannot be
+// null. The patched product must be valid as a CreateProductRequest.
+type PatchProductRequest struct {
+	SKU         patch.Field[string]      `json:"sku"`
+	Name        patch.Field[string]      `json:"name"`
+	Price       patch.Field[money.Money] `json:"price"`
+	Weight      patch.Field[float64]     `json:"weight"`
+	Colour      patch.Field[string]      `json:"colour"`
+	Description patch.Field[string]      `json:"description"`
+	Stock       patch.Field[int]         `json:"stock"`
+	TaxClass    patch.Field[string]      `json:"tax_class"` // null resets it to STANDARD
+	CategoryIDs patch.Field[[]int]       `json:"category_ids"`
+	Status      patch.Field[string]      `json:"status"`
+	PublishAt   patch.Field[*time.Time]  `json:"publish_at"`
+	UnpublishAt patch.Field[*time.Time]  `json:"unpublish_at"`
+}
+
+// SetStatusRequest sets a product's status and publishing schedule. Leaving
+// out a time clears it.
+type SetStatusRequest struct {
+	Status      string     `json:"status" binding:"required,oneof=draft published archived"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+}
+
+type SetCategoriesRequest struct {
+	CategoryIDs []int `json:"category_ids" binding:"required"`
+}
+
+type UpdateStockRequest struct {
+	Stock *int `json:"stock" binding:"required,gte=0"`
+}
+
+// ProductFilter narrows, orders and pages the product listing. Nil bounds
+// and empty strings are not applied.
+type ProductFilter struct {
+	Query     string // case-insensitive match on name
+	MinPrice  *money.Money
+	MaxPrice  *money.Money
+	MinWeight *float64
+	MaxWeight *float64
+	Colour    string
+	Category  string // ID or slug; includes products in its subcategories
+	Sort      string // price, name or created_at
+	Desc      bool
+	Limit     int
+	Offset    int
+
+	// AllStatuses lists products customers cannot see too, optionally only
+	// those with Status (admin only)
+	AllStatuses bool
+	Status      string
+	// Deleted lists the products in the trash instead (admin only)
+	Deleted bool
+
+	categoryID int // Category, resolved by the service
+}
+
+// ProductPage is one page of the product listing
+type ProductPage struct {
+	Data   []Product `json:"data"`
+	Total  int64     `json:"total"` // matching products across all pages
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
+// normalizeTaxClass makes tax classes case-insensitive, as tax rules are
+func normalizeTaxClass(taxClass string) string {
+	return strings.ToUpper(strings.TrimSpace(taxClass))
+}
+
+// SearchResult is a product matched by full-text search. The highlights are
+// HTML-escaped, with matched words wrapped in <mark> tags.
+type SearchResult struct {
+	Product
+	Rank          float64 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"` // matching fragment of the description
+}
+
+// SearchPage is one page of full-text search results, best match first
+type SearchPage struct {
+	Data   []SearchResult `json:"data"`
+	Total  int64          `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// Suggestion is a product offered while the customer is still typing
+type Suggestion struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
 }
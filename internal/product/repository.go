@@ -1,15 +1,46 @@
 package product
 
 import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mini-ecommerce/internal/category"
+	"mini-ecommerce/pkg/money"
 )
 
 type ProductRepository interface {
-	Create(product *Product) error
-	FindAll() ([]Product, error)
+	Create(product *Product, changedBy int) error
+	FindAll(filter ProductFilter) ([]Product, int64, error)
 	FindByID(id int) (*Product, error)
-	Update(id int, product *Product) error
+	FindBySKUs(skus []string) ([]Product, error)
+	FindByName(name string) ([]Product, error)
+	FindInBatches(batchSize int, fn func(products []Product) error) error
+	Search(query string, limit int, offset int) ([]SearchResult, int64, error)
+	Suggest(prefix string, limit int) ([]Suggestion, error)
+	Update(id int, product *Product, changedBy int) error
+	UpdateStock(id int, stock int) error
+	SetStatus(id int, status string, publishAt *time.Time, unpublishAt *time.Time) error
+	SetRating(id int, average float64, count int) error
 	Delete(id int) error
+	FindDeleted(id int) (*Product, error)
+	Restore(id int) error
+	Purge(id int) error
+	CreateVariants(variants []Variant) error
+	FindVariant(productID int, id int) (*Variant, error)
+	FindVariantsBySKU(skus []string) ([]Variant, error)
+	UpdateVariant(id int, variant *Variant) error
+	DeleteVariant(productID int, id int) error
+	CreateImages(images []Image) error
+	FindImages(productID int) ([]Image, error)
+	ReorderImages(productID int, ids []int) error
+	DeleteImage(productID int, id int) error
+	FindPriceHistory(productID int) ([]PriceChange, error)
+	FindPriceAt(productID int, at time.Time) (*PriceChange, error)
 }
 
 type productRepository struct {
@@ -20,29 +51,527 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	return &productRepository{db: db}
 }
 
-func (r *productRepository) Create(product *Product) error {
-	return r.db.Create(product).Error
+// Create saves a new product and opens its price history, attributing the
+// first price to changedBy
+func (r *productRepository) Create(product *Product, changedBy int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(product).Error; err != nil {
+			return err
+		}
+		if err := replaceCategories(tx, product.ID, product.Categories); err != nil {
+			return err
+		}
+		if err := recordPrice(tx, product.ID, product.Price, product.CreatedAt, changedBy); err != nil {
+			return err
+		}
+		return refreshSearchVector(tx, product.ID)
+	})
 }
 
-func (r *productRepository) FindAll() ([]Product, error) {
+// sortColumns maps listing sort keys to columns; anything else is rejected
+// by the service before it gets here
+var sortColumns = map[string]string{
+	SortCreatedAt: "created_at",
+	SortName:      "name",
+	SortPrice:     "price_amount",
+}
+
+// FindAll returns one page of products matching filter along with the
+// number of matches across all pages
+func (r *productRepository) FindAll(filter ProductFilter) ([]Product, int64, error) {
+	query := r.db.Model(&Product{})
+	if filter.Query != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price_amount >= ?", filter.MinPrice.Amount)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price_amount <= ?", filter.MaxPrice.Amount)
+	}
+	if filter.MinWeight != nil {
+		query = query.Where("weight >= ?", *filter.MinWeight)
+	}
+	if filter.MaxWeight != nil {
+		query = query.Where("weight <= ?", *filter.MaxWeight)
+	}
+	if filter.Colour != "" {
+		query = query.Where("LOWER(colour) = LOWER(?)", filter.Colour)
+	}
+	if filter.categoryID > 0 {
+		query = query.Where("id IN (SELECT product_id FROM product_categories WHERE category_id IN ("+
+			category.SubtreeIDsSQL+"))", filter.categoryID)
+	}
+	switch {
+	case filter.Deleted:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	case !filter.AllStatuses:
+		query = query.Scopes(publishedAt(time.Now()))
+	case filter.Status != "":
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	// A new session lets the count and the page share the conditions
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := sortColumns[filter.Sort]
+	if !ok {
+		column = sortColumns[SortCreatedAt]
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+	// id breaks ties so pages stay stable between requests
+	order := column + " " + direction + ", id " + direction
+
 	var products []Product
-	err := r.db.Find(&products).Error
-	return products, err
+	err := query.Preload("Categories", orderCategories).Preload("Variants", orderVariants).
+		Preload("Images", orderImages).
+		Order(order).Limit(filter.Limit).Offset(filter.Offset).Find(&products).Error
+	for i := range products {
+		products[i].resolveVariants()
+	}
+	return products, total, err
 }
 
 func (r *productRepository) FindByID(id int) (*Product, error) {
 	var product Product
-	err := r.db.First(&product, id).Error
+	err := r.db.Preload("Categories", orderCategories).Preload("Variants", orderVariants).
+		Preload("Images", orderImages).
+		First(&product, id).Error
 	if err != nil {
 		return nil, err
 	}
+	product.resolveVariants()
 	return &product, nil
 }
 
-func (r *productRepository) Update(id int, product *Product) error {
-	return r.db.Model(&Product{}).Where("id = ?", id).Updates(product).Error
+// FindBySKUs returns the products with the given SKUs, including deleted
+// ones, which keep their SKU until purged
+func (r *productRepository) FindBySKUs(skus []string) ([]Product, error) {
+	var products []Product
+	if len(skus) == 0 {
+		return products, nil
+	}
+	err := r.db.Unscoped().Where("sku IN ?", skus).Find(&products).Error
+	return products, err
+}
+
+// FindByName returns the products named name, ignoring case
+func (r *productRepository) FindByName(name string) ([]Product, error) {
+	var products []Product
+	err := r.db.Preload("Categories", orderCategories).
+		Where("LOWER(name) = LOWER(?)", name).Order("id").Find(&products).Error
+	return products, err
+}
+
+// FindInBatches calls fn with every product and its categories, batchSize
+// products at a time in ID order, stopping at the first error
+func (r *productRepository) FindInBatches(batchSize int, fn func(products []Product) error) error {
+	var products []Product
+	return r.db.Preload("Categories", orderCategories).
+		FindInBatches(&products, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(products)
+		}).Error
+}
+
+// Search highlights mark matches with control characters, which are swapped
+// for <mark> tags once the rest of the text has been HTML-escaped
+const (
+	headlineStart   = "\x02"
+	headlineStop    = "\x03"
+	headlineOptions = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", MaxFragments=2, MaxWords=20, MinWords=5`
+)
+
+var headlineMarks = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+// markHighlight HTML-escapes a highlight from ts_headline and marks its matches
+func markHighlight(highlight string) string {
+	return headlineMarks.Replace(html.EscapeString(highlight))
+}
+
+// Search ranks products against a web-style query such as
+// `red "apple juice" -diet`
+func (r *productRepository) Search(query string, limit int, offset int) ([]SearchResult, int64, error) {
+	matches := func() *gorm.DB {
+		return r.db.Model(&Product{}).
+			Joins("CROSS JOIN websearch_to_tsquery('english', ?) AS query", query).
+			Where("products.search_vector @@ query").
+			Scopes(publishedAt(time.Now()))
+	}
+
+	var total int64
+	if err := matches().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var results []SearchResult
+	err := matches().
+		Select(`products.*, ts_rank(products.search_vector, query) AS rank,
+			ts_headline('english', products.name, query, ?) AS name_highlight,
+			ts_headline('english', products.description, query, ?) AS snippet`,
+			headlineOptions, headlineOptions).
+		Order("rank DESC, products.id").
+		Limit(limit).Offset(offset).
+		Find(&results).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range results {
+		results[i].NameHighlight = markHighlight(results[i].NameHighlight)
+		results[i].Snippet = markHighlight(results[i].Snippet)
+	}
+	return results, total, nil
+}
+
+// Suggest finds products with words starting with each word of prefix, as
+// for a search box being typed into. prefix must already be a to_tsquery
+// expression such as "red:* & app:*".
+func (r *productRepository) Suggest(prefix string, limit int) ([]Suggestion, error) {
+	var suggestions []Suggestion
+	err := r.db.Model(&Product{}).
+		Select("products.id, products.name").
+		Joins("CROSS JOIN to_tsquery('english', ?) AS query", prefix).
+		Where("products.search_vector @@ query").
+		Scopes(publishedAt(time.Now())).
+		Order("ts_rank(products.search_vector, query) DESC, products.name").
+		Limit(limit).
+		Find(&suggestions).Error
+	return suggestions, err
+}
+
+// Update saves all of a product's fields, including empty ones, and its
+// categories. Stock and ratings are left alone: they have their own updates.
+// If the price changes, the change is added to the price history and
+// attributed to changedBy.
+func (r *productRepository) Update(id int, product *Product, changedBy int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent price changes are recorded in the order they are made
+		var current Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "price_amount", "price_currency").
+			First(&current, id).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&Product{}).Where("id = ?", id).Select("*").
+			Omit(clause.Associations, "id", "created_at", "deleted_at", "stock", "rating_average", "rating_count").
+			Updates(product).Error
+		if err != nil {
+			return err
+		}
+		if !product.Price.IsZero() && product.Price.OrDefault() != current.Price.OrDefault() {
+			if err := recordPrice(tx, id, product.Price, time.Now(), changedBy); err != nil {
+				return err
+			}
+		}
+		if err := replaceCategories(tx, id, product.Categories); err != nil {
+			return err
+		}
+		return refreshSearchVector(tx, id)
+	})
+}
+
+func (r *productRepository) UpdateStock(id int, stock int) error {
+	result := r.db.Model(&Product{}).Where("id = ?", id).Update("stock", stock)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
+// SetStatus saves a product's status and publishing schedule, clearing
+// schedule times that are nil
+func (r *productRepository) SetStatus(id int, status string, publishAt *time.Time, unpublishAt *time.Time) error {
+	result := r.db.Model(&Product{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       status,
+		"publish_at":   publishAt,
+		"unpublish_at": unpublishAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetRating saves a product's review summary without touching updated_at
+func (r *productRepository) SetRating(id int, average float64, count int) error {
+	return r.db.Model(&Product{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"rating_average": average,
+		"rating_count":   count,
+	}).Error
+}
+
+// Delete moves a product to the trash
 func (r *productRepository) Delete(id int) error {
-	return r.db.Delete(&Product{}, id).Error
+	result := r.db.Delete(&Product{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindDeleted returns a product in the trash with its images
+func (r *productRepository) FindDeleted(id int) (*Product, error) {
+	var product Product
+	err := r.db.Unscoped().Preload("Images", orderImages).
+		Where("deleted_at IS NOT NULL").First(&product, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// Restore takes a product out of the trash
+func (r *productRepository) Restore(id int) error {
+	result := r.db.Unscoped().Model(&Product{}).Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge permanently deletes a product in the trash along with its variants,
+// images, categories and price history. It fails with ErrProductInUse if
+// any order includes the product.
+func (r *productRepository) Purge(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so no order can be placed for it in between; deleted
+		// products cannot be ordered anyway
+		var product Product
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("deleted_at IS NOT NULL").First(&product, id).Error
+		if err != nil {
+			return err
+		}
+
+		var orders int64
+		err = tx.Table("order_items").Where("product_id = ?", id).Distinct("order_id").Count(&orders).Error
+		if err != nil {
+			return err
+		}
+		if orders > 0 {
+			return fmt.Errorf("%w: %d orders include it", ErrProductInUse, orders)
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&PriceChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Select(clause.Associations).Delete(&product).Error
+	})
+}
+
+// escapeLike stops user input from being read as LIKE wildcards
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *productRepository) CreateVariants(variants []Variant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	return r.db.Create(&variants).Error
+}
+
+func (r *productRepository) FindVariant(productID int, id int) (*Variant, error) {
+	var variant Variant
+	err := r.db.Where("product_id = ?", productID).First(&variant, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *productRepository) FindVariantsBySKU(skus []string) ([]Variant, error) {
+	var variants []Variant
+	if len(skus) == 0 {
+		return variants, nil
+	}
+	err := r.db.Where("sku IN ?", skus).Find(&variants).Error
+	return variants, err
+}
+
+// UpdateVariant saves every variant field, including zero values
+func (r *productRepository) UpdateVariant(id int, variant *Variant) error {
+	result := r.db.Model(&Variant{}).Where("id = ?", id).
+		Select("*").Omit("id", "product_id", "created_at").
+		Updates(variant)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteVariant deletes a variant and takes it out of any carts. Like Purge,
+// it fails with ErrVariantInUse if any order includes the variant.
+func (r *productRepository) DeleteVariant(productID int, id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Orders lock the variant row while reserving its stock
+		var variant Variant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("product_id = ?", productID).First(&variant, id).Error
+		if err != nil {
+			return err
+		}
+
+		var orders int64
+		err = tx.Table("order_items").Where("variant_id = ?", id).Distinct("order_id").Count(&orders).Error
+		if err != nil {
+			return err
+		}
+		if orders > 0 {
+			return fmt.Errorf("%w: %d orders include it", ErrVariantInUse, orders)
+		}
+
+		if err := tx.Exec("DELETE FROM cart_items WHERE variant_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&variant).Error
+	})
+}
+
+func (r *productRepository) CreateImages(images []Image) error {
+	if len(images) == 0 {
+		return nil
+	}
+	return r.db.Create(&images).Error
+}
+
+// FindImages returns a product's images in gallery order
+func (r *productRepository) FindImages(productID int) ([]Image, error) {
+	var images []Image
+	err := r.db.Where("product_id = ?", productID).Scopes(orderImages).Find(&images).Error
+	return images, err
+}
+
+// ReorderImages numbers a product's images in the order of ids
+func (r *productRepository) ReorderImages(productID int, ids []int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			err := tx.Model(&Image{}).Where("id = ? AND product_id = ?", id, productID).
+				Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *productRepository) DeleteImage(productID int, id int) error {
+	result := r.db.Where("product_id = ?", productID).Delete(&Image{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindPriceHistory returns a product's price changes, oldest first
+func (r *productRepository) FindPriceHistory(productID int) ([]PriceChange, error) {
+	var history []PriceChange
+	err := r.db.Where("product_id = ?", productID).Order("effective_from, id").Find(&history).Error
+	return history, err
+}
+
+// FindPriceAt returns the price change in effect at a given time
+func (r *productRepository) FindPriceAt(productID int, at time.Time) (*PriceChange, error) {
+	var change PriceChange
+	err := r.db.Where("product_id = ? AND effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)",
+		productID, at, at).
+		Order("effective_from DESC, id DESC").First(&change).Error
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// recordPrice ends a product's current price period and starts a new one
+// at the given time
+func recordPrice(tx *gorm.DB, productID int, price money.Money, at time.Time, changedBy int) error {
+	err := tx.Model(&PriceChange{}).Where("product_id = ? AND effective_to IS NULL", productID).
+		Update("effective_to", at).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(&PriceChange{
+		ProductID:     productID,
+		Price:         price,
+		EffectiveFrom: at,
+		ChangedBy:     changedBy,
+	}).Error
+}
+
+// productCategory is a row of the product_categories join table
+type productCategory struct {
+	ProductID  int
+	CategoryID int
+}
+
+func (productCategory) TableName() string {
+	return "product_categories"
+}
+
+// replaceCategories makes categories the product's only categories
+func replaceCategories(tx *gorm.DB, productID int, categories []category.Category) error {
+	if err := tx.Where("product_id = ?", productID).Delete(&productCategory{}).Error; err != nil {
+		return err
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	rows := make([]productCategory, len(categories))
+	for i, c := range categories {
+		rows[i] = productCategory{ProductID: productID, CategoryID: c.ID}
+	}
+	return tx.Create(&rows).Error
+}
+
+// publishedAt limits a query to the products customers can see at a time
+func publishedAt(at time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("products.status = ? AND (products.publish_at IS NULL OR products.publish_at <= ?) AND "+
+			"(products.unpublish_at IS NULL OR products.unpublish_at > ?)", StatusPublished, at, at)
+	}
+}
+
+func orderCategories(db *gorm.DB) *gorm.DB {
+	return db.Order("categories.position, categories.id")
+}
+
+func orderVariants(db *gorm.DB) *gorm.DB {
+	return db.Order("product_variants.id")
+}
+
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("product_images.position, product_images.id")
+}
+
+// refreshSearchVector rebuilds a product's search document from its saved
+// name and description
+func refreshSearchVector(tx *gorm.DB, id int) error {
+	return tx.Exec("UPDATE products SET search_vector = "+SearchVectorSQL+" WHERE id = ?", id).Error
 }
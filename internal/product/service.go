@@ -1,73 +1,1148 @@
 package product
 
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"mini-ecommerce/internal/category"
+	"mini-ecommerce/pkg/imaging"
+	"mini-ecommerce/pkg/money"
+	"mini-ecommerce/pkg/storage"
+	"mini-ecommerce/pkg/validation"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ErrInvalidPrice is returned for prices that are not positive or not in the
+// store currency
+var ErrInvalidPrice = errors.New("invalid price")
+
+// ErrInvalidCategory is returned when assigning a category that does not exist
+var ErrInvalidCategory = errors.New("invalid category")
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrVariantNotFound = errors.New("variant not found")
+	// ErrVariantRequired is returned when buying a product that has variants
+	// without choosing one
+	ErrVariantRequired = errors.New("a variant must be chosen")
+	ErrInvalidVariant  = errors.New("invalid variant")
+	ErrSKUTaken        = errors.New("sku already exists")
+	// ErrProductInUse is returned when purging a product that orders include
+	ErrProductInUse = errors.New("product is in use")
+	// ErrVariantInUse is returned when deleting a variant that orders include
+	ErrVariantInUse = errors.New("variant is in use")
+)
+
+// exportBatchSize is how many products an export loads at a time
+const exportBatchSize = 500
+
+// maxGeneratedVariants caps the variant matrix so that a mistyped option
+// list cannot create thousands of rows
+const maxGeneratedVariants = 200
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+	// ErrInvalidImage is returned for uploads that are not a supported image,
+	// and for galleries that would grow past MaxProductImages
+	ErrInvalidImage = errors.New("invalid image")
+)
+
+// ErrInvalidFilter is returned for product listing parameters that cannot be applied
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ErrInvalidSchedule is returned when a product would be unpublished before
+// it is published
+var ErrInvalidSchedule = errors.New("invalid publishing schedule")
+
+// ErrInvalidPatch is returned for product patches that cannot be applied, or
+// that leave the product invalid
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// ErrPriceNotFound is returned when asking for a product's price at a time
+// before its price history begins
+var ErrPriceNotFound = errors.New("no price recorded at that time")
+
 type ProductService interface {
-	CreateProduct(req CreateProductRequest) (*Product, error)
-	GetAllProducts() ([]Product, error)
-	GetProductByID(id int) (*Product, error)
-	UpdateProduct(id int, req UpdateProductRequest) (*Product, error)
+	CreateProduct(req CreateProductRequest, adminID int) (*Product, error)
+	GetAllProducts(filter ProductFilter) (*ProductPage, error)
+	GetProductByID(id int, allStatuses bool) (*Product, error)
+	SearchProducts(query string, limit int, offset int) (*SearchPage, error)
+	SuggestProducts(prefix string, limit int) ([]Suggestion, error)
+	UpdateProduct(id int, req ReplaceProductRequest, adminID int) (*Product, error)
+	PatchProduct(id int, req PatchProductRequest, adminID int) (*Product, error)
+	UpdateStock(id int, req UpdateStockRequest) (*Product, error)
+	SetCategories(id int, req SetCategoriesRequest) (*Product, error)
+	SetStatus(id int, req SetStatusRequest) (*Product, error)
 	DeleteProduct(id int) error
+	RestoreProduct(id int) (*Product, error)
+	PurgeProduct(id int) error
+	CreateVariant(productID int, req VariantRequest) (*Variant, error)
+	GenerateVariants(productID int, req GenerateVariantsRequest) ([]Variant, error)
+	UpdateVariant(productID int, id int, req VariantRequest) (*Variant, error)
+	DeleteVariant(productID int, id int) error
+	AddImages(productID int, uploads []ImageUpload) ([]Image, error)
+	ReorderImages(productID int, req ReorderImagesRequest) ([]Image, error)
+	DeleteImage(productID int, id int) error
+	ImportProducts(r io.Reader, format string, dryRun bool, adminID int) (*ImportReport, error)
+	ExportProducts(w io.Writer, format string) error
+	GetPriceHistory(productID int) ([]PriceChange, error)
+	GetPriceAt(productID int, at time.Time) (*PriceChange, error)
 }
 
 type productService struct {
-	repo ProductRepository
+	repo         ProductRepository
+	categoryRepo category.CategoryRepository
+	storage      storage.Storage
+	sales        SalePricer
 }
 
-func NewProductService(repo ProductRepository) ProductService {
-	return &productService{repo: repo}
+func NewProductService(repo ProductRepository, categoryRepo category.CategoryRepository, store storage.Storage, sales SalePricer) ProductService {
+	return &productService{repo: repo, categoryRepo: categoryRepo, storage: store, sales: sales}
 }
 
-func (s *productService) CreateProduct(req CreateProductRequest) (*Product, error) {
-	product := &Product{
-		Name:        req.Name,
-		Price:       req.Price,
-		Weight:      req.Weight,
-		Colour:      req.Colour,
-		Description: req.Description,
+// CreateProduct adds a product, recording its price as set by adminID
+func (s *productService) CreateProduct(req CreateProductRequest, adminID int) (*Product, error) {
+	product, err := s.productFromRequest(req)
+	if err != nil {
+		return nil, err
 	}
-	err := s.repo.Create(product)
+	if err := s.checkSKU(product.SKU, 0); err != nil {
+		return nil, err
+	}
+	err = s.repo.Create(product, adminID)
 	if err != nil {
 		return nil, err
 	}
 	return product, nil
 }
 
-func (s *productService) GetAllProducts() ([]Product, error) {
-	return s.repo.FindAll()
+func (s *productService) GetAllProducts(filter ProductFilter) (*ProductPage, error) {
+	if err := normalizeFilter(&filter); err != nil {
+		return nil, err
+	}
+	if filter.Category != "" {
+		c, err := s.categoryRepo.FindByRef(filter.Category)
+		if err != nil {
+			return nil, category.ErrCategoryNotFound
+		}
+		filter.categoryID = c.ID
+	}
+
+	products, total, err := s.repo.FindAll(filter)
+	if err != nil {
+		return nil, err
+	}
+	if products == nil {
+		products = []Product{}
+	}
+	priced := make([]*Product, len(products))
+	for i := range products {
+		s.imageURLs(products[i].Images)
+		priced[i] = &products[i]
+	}
+	if err := s.sales.ApplySales(priced); err != nil {
+		return nil, err
+	}
+	return &ProductPage{Data: products, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
 }
 
-func (s *productService) GetProductByID(id int) (*Product, error) {
-	return s.repo.FindByID(id)
+// GetProductByID returns a product if customers can see it now, or in any
+// status with allStatuses
+func (s *productService) GetProductByID(id int, allStatuses bool) (*Product, error) {
+	product, err := s.product(id)
+	if err != nil {
+		return nil, err
+	}
+	if !allStatuses && !product.IsPublished(time.Now()) {
+		return nil, ErrProductNotFound
+	}
+	return product, nil
 }
 
-func (s *productService) UpdateProduct(id int, req UpdateProductRequest) (*Product, error) {
-	product, err := s.repo.FindByID(id)
+func (s *productService) SearchProducts(query string, limit int, offset int) (*SearchPage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidFilter)
+	}
+	if err := normalizePage(&limit, &offset, DefaultPageLimit, MaxPageLimit); err != nil {
+		return nil, err
+	}
+
+	results, total, err := s.repo.Search(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+	priced := make([]*Product, len(results))
+	for i := range results {
+		priced[i] = &results[i].Product
+	}
+	if err := s.sales.ApplySales(priced); err != nil {
+		return nil, err
+	}
+	return &SearchPage{Data: results, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (s *productService) SuggestProducts(prefix string, limit int) ([]Suggestion, error) {
+	offset := 0
+	if err := normalizePage(&limit, &offset, DefaultSuggestLimit, MaxSuggestLimit); err != nil {
+		return nil, err
+	}
+
+	query := prefixQuery(prefix)
+	if query == "" {
+		return []Suggestion{}, nil
+	}
+	suggestions, err := s.repo.Suggest(query, limit)
 	if err != nil {
 		return nil, err
 	}
+	if suggestions == nil {
+		suggestions = []Suggestion{}
+	}
+	return suggestions, nil
+}
 
-	if req.Name != "" {
-		product.Name = req.Name
+// UpdateProduct replaces every field of a product with those in req. A new
+// price is added to the product's price history as set by adminID.
+func (s *productService) UpdateProduct(id int, req ReplaceProductRequest, adminID int) (*Product, error) {
+	existing, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
 	}
-	if req.Price > 0 {
-		product.Price = req.Price
+	return s.replace(existing, req.createRequest(), true, adminID)
+}
+
+// PatchProduct applies a JSON Merge Patch to a product. The patched product
+// is checked as UpdateProduct would check it.
+func (s *productService) PatchProduct(id int, req PatchProductRequest, adminID int) (*Product, error) {
+	existing, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
 	}
-	if req.Weight > 0 {
-		product.Weight = req.Weight
+	if req.Name.Null || req.Price.Null || req.Weight.Null || req.Status.Null {
+		return nil, fmt.Errorf("%w: name, price, weight and status cannot be null", ErrInvalidPatch)
 	}
-	if req.Colour != "" {
-		product.Colour = req.Colour
+
+	merged := requestFromProduct(existing)
+	req.SKU.Apply(&merged.SKU)
+	req.Name.Apply(&merged.Name)
+	req.Price.Apply(&merged.Price)
+	req.Weight.Apply(&merged.Weight)
+	req.Colour.Apply(&merged.Colour)
+	req.Description.Apply(&merged.Description)
+	req.Stock.Apply(&merged.Stock)
+	req.TaxClass.Apply(&merged.TaxClass)
+	req.CategoryIDs.Apply(&merged.CategoryIDs)
+	req.Status.Apply(&merged.Status)
+	req.PublishAt.Apply(&merged.PublishAt)
+	req.UnpublishAt.Apply(&merged.UnpublishAt)
+	if err := binding.Validator.ValidateStruct(&merged); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, validation.Describe(err))
 	}
-	if req.Description != "" {
-		product.Description = req.Description
+	// Stock is only written when asked, so as not to undo reservations made
+	// since the product was read
+	return s.replace(existing, merged, req.Stock.Set, adminID)
+}
+
+// replace saves req over existing, leaving its stock alone unless setStock
+func (s *productService) replace(existing *Product, req CreateProductRequest, setStock bool, adminID int) (*Product, error) {
+	product, err := s.productFromRequest(req)
+	if err != nil {
+		return nil, err
 	}
+	if err := s.checkSKU(product.SKU, existing.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(existing.ID, product, adminID); err != nil {
+		return nil, err
+	}
+	if setStock {
+		if err := s.repo.UpdateStock(existing.ID, product.Stock); err != nil {
+			return nil, err
+		}
+	}
+	return s.product(existing.ID)
+}
 
-	err = s.repo.Update(id, product)
+func (s *productService) UpdateStock(id int, req UpdateStockRequest) (*Product, error) {
+	err := s.repo.UpdateStock(id, *req.Stock)
 	if err != nil {
 		return nil, err
 	}
-	return product, nil
+	return s.product(id)
+}
+
+// SetCategories replaces the categories a product is listed under
+func (s *productService) SetCategories(id int, req SetCategoriesRequest) (*Product, error) {
+	product, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if product.Categories, err = s.categories(req.CategoryIDs); err != nil {
+		return nil, err
+	}
+
+	// The price is unchanged, so no one is recorded as changing it
+	err = s.repo.Update(id, product, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.product(id)
+}
+
+// SetStatus publishes, unpublishes or archives a product, and sets when it
+// is shown
+func (s *productService) SetStatus(id int, req SetStatusRequest) (*Product, error) {
+	if err := validateSchedule(req.PublishAt, req.UnpublishAt); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetStatus(id, req.Status, req.PublishAt, req.UnpublishAt); err != nil {
+		return nil, err
+	}
+	return s.product(id)
 }
 
+// DeleteProduct moves a product to the trash. It keeps its images, so that
+// it can be restored.
 func (s *productService) DeleteProduct(id int) error {
 	return s.repo.Delete(id)
 }
+
+// RestoreProduct takes a product out of the trash, in the status it had
+func (s *productService) RestoreProduct(id int) (*Product, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return nil, ErrProductNotFound
+	}
+	return s.product(id)
+}
+
+// PurgeProduct permanently deletes a product in the trash and its image
+// files, unless an order includes it
+func (s *productService) PurgeProduct(id int) error {
+	product, err := s.repo.FindDeleted(id)
+	if err != nil {
+		return ErrProductNotFound
+	}
+	if err := s.repo.Purge(id); err != nil {
+		return err
+	}
+	for _, img := range product.Images {
+		s.deleteFiles(img.StorageKeys())
+	}
+	return nil
+}
+
+func (s *productService) CreateVariant(productID int, req VariantRequest) (*Variant, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	variant, err := variantFromRequest(product, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVariant(product, variant, 0); err != nil {
+		return nil, err
+	}
+
+	variants := []Variant{*variant}
+	if err := s.repo.CreateVariants(variants); err != nil {
+		return nil, err
+	}
+	return s.variant(productID, variants[0].ID)
+}
+
+// GenerateVariants creates the variants for every combination of option
+// values that the product does not have yet, and returns them
+func (s *productService) GenerateVariants(productID int, req GenerateVariantsRequest) ([]Variant, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	combinations, err := optionCombinations(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.ToUpper(category.Slugify(req.SKUPrefix))
+	if prefix == "" {
+		prefix = strings.ToUpper(category.Slugify(product.Name))
+	}
+
+	existing := make(map[string]bool, len(product.Variants))
+	for _, v := range product.Variants {
+		existing[v.AttributeKey] = true
+	}
+
+	var variants []Variant
+	var skus []string
+	generated := make(map[string]string, len(combinations)) // SKU to label
+	for _, attributes := range combinations {
+		variant, err := variantFromRequest(product, VariantRequest{
+			SKU:        prefix + "-" + attributeSKU(attributes),
+			Attributes: attributes,
+			Price:      req.Price,
+			Weight:     req.Weight,
+			Stock:      req.Stock,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Option values that differ only in spacing or punctuation, such as
+		// "X-L" and "X L", give the same SKU
+		if other, ok := generated[variant.SKU]; ok {
+			return nil, fmt.Errorf("%w: %q and %q would both get sku %s; rename one of the options",
+				ErrInvalidVariant, other, variant.Label(), variant.SKU)
+		}
+		generated[variant.SKU] = variant.Label()
+		if existing[variant.AttributeKey] {
+			continue
+		}
+		variants = append(variants, *variant)
+		skus = append(skus, variant.SKU)
+	}
+
+	taken, err := s.repo.FindVariantsBySKU(skus)
+	if err != nil {
+		return nil, err
+	}
+	if len(taken) > 0 {
+		return nil, fmt.Errorf("%w: %s; choose another sku_prefix", ErrSKUTaken, taken[0].SKU)
+	}
+	takenByProducts, err := s.repo.FindBySKUs(skus)
+	if err != nil {
+		return nil, err
+	}
+	if len(takenByProducts) > 0 {
+		return nil, fmt.Errorf("%w: %s; choose another sku_prefix", ErrSKUTaken, takenByProducts[0].SKU)
+	}
+
+	if err := s.repo.CreateVariants(variants); err != nil {
+		return nil, err
+	}
+	product.Variants = variants
+	product.resolveVariants()
+	if product.Variants == nil {
+		product.Variants = []Variant{}
+	}
+	return product.Variants, nil
+}
+
+// UpdateVariant replaces a variant's SKU, attributes, price, weight and stock
+func (s *productService) UpdateVariant(productID int, id int, req VariantRequest) (*Variant, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	if _, err := s.repo.FindVariant(productID, id); err != nil {
+		return nil, ErrVariantNotFound
+	}
+	variant, err := variantFromRequest(product, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVariant(product, variant, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateVariant(id, variant); err != nil {
+		return nil, err
+	}
+	return s.variant(productID, id)
+}
+
+func (s *productService) DeleteVariant(productID int, id int) error {
+	if _, err := s.repo.FindVariant(productID, id); err != nil {
+		return ErrVariantNotFound
+	}
+	return s.repo.DeleteVariant(productID, id)
+}
+
+// ImportProducts creates or updates a product for every record of a CSV or
+// JSON Lines file. Records are matched to existing products by SKU, or by
+// name when they have none. Each is validated like CreateProduct and saved
+// on its own, so bad records are reported without stopping the import. With
+// dryRun nothing is saved. Price changes are recorded as made by adminID.
+func (s *productService) ImportProducts(r io.Reader, format string, dryRun bool, adminID int) (*ImportReport, error) {
+	records, err := newRecordReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: dryRun, Errors: []RowError{}}
+	for {
+		rec, line, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, ErrInvalidImport) {
+			// The rest of the file cannot be read, but earlier records are saved
+			report.Failed++
+			report.Errors = append(report.Errors, RowError{Line: line, Error: err.Error()})
+			break
+		}
+		if err == nil {
+			var created bool
+			created, err = s.importRecord(rec, dryRun, adminID)
+			switch {
+			case err == nil && created:
+				report.Created++
+			case err == nil:
+				report.Updated++
+			}
+		}
+		if err != nil {
+			if !isRecordError(err) {
+				return nil, err
+			}
+			report.Failed++
+			report.Errors = append(report.Errors, RowError{Line: line, Error: err.Error()})
+		}
+	}
+	return report, nil
+}
+
+// importRecord saves one import record, reporting whether it made a new
+// product
+func (s *productService) importRecord(rec *productRecord, dryRun bool, adminID int) (bool, error) {
+	req := &rec.CreateProductRequest
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return false, fmt.Errorf("%w: %s", ErrInvalidRecord, validation.Describe(err))
+	}
+	product, err := s.productFromRequest(*req)
+	if err != nil {
+		return false, err
+	}
+	existing, err := s.importMatch(product)
+	if err != nil {
+		return false, err
+	}
+
+	if existing == nil {
+		if err := s.checkSKU(product.SKU, 0); err != nil {
+			return false, err
+		}
+		if dryRun {
+			return true, nil
+		}
+		return true, s.repo.Create(product, adminID)
+	}
+
+	// Fields a record leaves empty keep their current values
+	if product.SKU == "" {
+		product.SKU = existing.SKU
+	}
+	if err := s.checkSKU(product.SKU, existing.ID); err != nil {
+		return false, err
+	}
+	if product.Colour == "" {
+		product.Colour = existing.Colour
+	}
+	if product.Description == "" {
+		product.Description = existing.Description
+	}
+	if req.CategoryIDs == nil {
+		product.Categories = existing.Categories
+	}
+	if req.Status == "" {
+		product.Status = existing.Status
+	}
+	if product.PublishAt == nil {
+		product.PublishAt = existing.PublishAt
+	}
+	if product.UnpublishAt == nil {
+		product.UnpublishAt = existing.UnpublishAt
+	}
+	if dryRun {
+		return false, nil
+	}
+	if err := s.repo.Update(existing.ID, product, adminID); err != nil {
+		return false, err
+	}
+	// Update leaves stock alone; a record without a stock value keeps it too
+	if !rec.HasStock {
+		return false, nil
+	}
+	return false, s.repo.UpdateStock(existing.ID, product.Stock)
+}
+
+// importMatch finds the product an import record updates: the one with its
+// SKU, or else the only one with its name. It returns nil for new products.
+func (s *productService) importMatch(product *Product) (*Product, error) {
+	var matches []Product
+	var err error
+	if product.SKU != "" {
+		matches, err = s.repo.FindBySKUs([]string{product.SKU})
+	} else {
+		matches, err = s.repo.FindByName(product.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		if matches[0].DeletedAt.Valid {
+			return nil, fmt.Errorf("%w: sku %s belongs to product %d in the trash; restore or purge it", ErrInvalidRecord, product.SKU, matches[0].ID)
+		}
+		return s.repo.FindByID(matches[0].ID)
+	default:
+		return nil, fmt.Errorf("%w: %d products are named %q; give a sku", ErrInvalidRecord, len(matches), product.Name)
+	}
+}
+
+// isRecordError reports whether err is a problem with one import record
+// rather than with the import as a whole
+func isRecordError(err error) bool {
+	return errors.Is(err, ErrInvalidRecord) || errors.Is(err, ErrInvalidPrice) ||
+		errors.Is(err, ErrInvalidCategory) || errors.Is(err, ErrSKUTaken) ||
+		errors.Is(err, ErrInvalidSchedule)
+}
+
+// ExportProducts writes the whole catalogue as CSV or JSON Lines, in the
+// form ImportProducts reads, loading a batch of products at a time
+func (s *productService) ExportProducts(w io.Writer, format string) error {
+	records, err := newRecordWriter(w, format)
+	if err != nil {
+		return err
+	}
+	err = s.repo.FindInBatches(exportBatchSize, func(products []Product) error {
+		for i := range products {
+			if err := records.Write(recordFromProduct(&products[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return records.Flush()
+}
+
+// GetPriceHistory returns a product's price timeline, oldest first
+func (s *productService) GetPriceHistory(productID int) ([]PriceChange, error) {
+	if _, err := s.repo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	history, err := s.repo.FindPriceHistory(productID)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []PriceChange{}
+	}
+	return history, nil
+}
+
+// GetPriceAt returns the price a product had at the given time
+func (s *productService) GetPriceAt(productID int, at time.Time) (*PriceChange, error) {
+	if _, err := s.repo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	change, err := s.repo.FindPriceAt(productID, at)
+	if err != nil {
+		return nil, ErrPriceNotFound
+	}
+	return change, nil
+}
+
+// AddImages stores uploaded images and their thumbnails, and appends them
+// to the product's gallery
+func (s *productService) AddImages(productID int, uploads []ImageUpload) ([]Image, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	if len(uploads) == 0 {
+		return nil, fmt.Errorf("%w: no image uploaded", ErrInvalidImage)
+	}
+	if len(product.Images)+len(uploads) > MaxProductImages {
+		return nil, fmt.Errorf("%w: a product can have at most %d images", ErrInvalidImage, MaxProductImages)
+	}
+
+	images := make([]Image, 0, len(uploads))
+	var saved []string
+	for i, upload := range uploads {
+		img, keys, err := s.storeImage(productID, upload)
+		saved = append(saved, keys...)
+		if err != nil {
+			s.deleteFiles(saved)
+			return nil, err
+		}
+		img.Position = len(product.Images) + i
+		images = append(images, *img)
+	}
+
+	if err := s.repo.CreateImages(images); err != nil {
+		s.deleteFiles(saved)
+		return nil, err
+	}
+	s.imageURLs(images)
+	return images, nil
+}
+
+// ReorderImages puts a product's gallery in the order of req.ImageIDs, which
+// must list each of its images once
+func (s *productService) ReorderImages(productID int, req ReorderImagesRequest) ([]Image, error) {
+	if _, err := s.repo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	images, err := s.repo.FindImages(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make([]int, len(images))
+	for i, img := range images {
+		current[i] = img.ID
+	}
+	requested := slices.Sorted(slices.Values(req.ImageIDs))
+	if !slices.Equal(requested, slices.Sorted(slices.Values(current))) {
+		return nil, fmt.Errorf("%w: image_ids must list each of the product's images once", ErrInvalidImage)
+	}
+
+	if err := s.repo.ReorderImages(productID, req.ImageIDs); err != nil {
+		return nil, err
+	}
+	images, err = s.repo.FindImages(productID)
+	if err != nil {
+		return nil, err
+	}
+	s.imageURLs(images)
+	return images, nil
+}
+
+// DeleteImage removes an image from a product's gallery along with its files
+func (s *productService) DeleteImage(productID int, id int) error {
+	images, err := s.repo.FindImages(productID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(images, func(img Image) bool { return img.ID == id })
+	if i < 0 {
+		return ErrImageNotFound
+	}
+	if err := s.repo.DeleteImage(productID, id); err != nil {
+		return ErrImageNotFound
+	}
+	s.deleteFiles(images[i].StorageKeys())
+	return nil
+}
+
+// storeImage decodes an upload and saves it with its thumbnails. It returns
+// the keys saved so far even when it fails, so the caller can remove them.
+func (s *productService) storeImage(productID int, upload ImageUpload) (*Image, []string, error) {
+	decoded, contentType, err := imaging.Decode(upload.Data, MaxImagePixels)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	name, err := randomName()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bounds := decoded.Bounds()
+	img := &Image{
+		ProductID:   productID,
+		Key:         fmt.Sprintf("products/%d/%s%s", productID, name, imaging.Extension(contentType)),
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		AltText:     strings.TrimSpace(upload.AltText),
+	}
+
+	var saved []string
+	if err := s.storage.Save(img.Key, bytes.NewReader(upload.Data), contentType); err != nil {
+		return nil, saved, err
+	}
+	saved = append(saved, img.Key)
+
+	thumbnailType := imaging.ThumbnailType(contentType)
+	for _, size := range ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Fit(decoded, size.Pixels), thumbnailType); err != nil {
+			return nil, saved, err
+		}
+		key := img.ThumbnailKey(size.Name)
+		if err := s.storage.Save(key, &buf, thumbnailType); err != nil {
+			return nil, saved, err
+		}
+		saved = append(saved, key)
+	}
+	return img, saved, nil
+}
+
+// deleteFiles removes stored files. Failures are only logged: a leftover
+// file is harmless, and the database change has already been made.
+func (s *productService) deleteFiles(keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(key); err != nil {
+			log.Printf("failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
+
+// imageURLs fills in where clients can fetch each image and its thumbnails
+func (s *productService) imageURLs(images []Image) {
+	for i := range images {
+		img := &images[i]
+		img.URL = s.storage.URL(img.Key)
+		img.Thumbnails = make(map[string]string, len(ThumbnailSizes))
+		for _, size := range ThumbnailSizes {
+			img.Thumbnails[size.Name] = s.storage.URL(img.ThumbnailKey(size.Name))
+		}
+	}
+}
+
+// product loads a product with its image URLs and any sale price filled in
+func (s *productService) product(id int) (*Product, error) {
+	product, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.imageURLs(product.Images)
+	if err := s.sales.ApplySales([]*Product{product}); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// randomName makes an unguessable file name, so that uploads never collide
+// and cannot be enumerated
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// variant loads a variant with its price and weight resolved
+func (s *productService) variant(productID int, id int) (*Variant, error) {
+	product, err := s.repo.FindByID(productID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	sel, err := product.Select(id)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Variant, nil
+}
+
+// checkVariant rejects a SKU used by another variant, and attributes that
+// another variant of the product already has. id is the variant being
+// updated, or 0.
+func (s *productService) checkVariant(product *Product, variant *Variant, id int) error {
+	taken, err := s.repo.FindVariantsBySKU([]string{variant.SKU})
+	if err != nil {
+		return err
+	}
+	if len(taken) > 0 && taken[0].ID != id {
+		return fmt.Errorf("%w: %s", ErrSKUTaken, variant.SKU)
+	}
+	products, err := s.repo.FindBySKUs([]string{variant.SKU})
+	if err != nil {
+		return err
+	}
+	if len(products) > 0 {
+		return fmt.Errorf("%w: %s", ErrSKUTaken, variant.SKU)
+	}
+	for _, v := range product.Variants {
+		if v.AttributeKey == variant.AttributeKey && v.ID != id {
+			return fmt.Errorf("%w: %s already has a %s variant", ErrInvalidVariant, product.Name, v.Label())
+		}
+	}
+	return nil
+}
+
+// variantFromRequest validates and normalises a variant of product.
+// Attribute names are lower-cased; SKUs are upper-cased.
+func variantFromRequest(product *Product, req VariantRequest) (*Variant, error) {
+	variant := &Variant{
+		ProductID:      product.ID,
+		SKU:            normalizeSKU(req.SKU),
+		Attributes:     make(map[string]string, len(req.Attributes)),
+		WeightOverride: req.Weight,
+		Stock:          req.Stock,
+	}
+	if variant.SKU == "" {
+		return nil, fmt.Errorf("%w: sku is required", ErrInvalidVariant)
+	}
+	for name, value := range req.Attributes {
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			return nil, fmt.Errorf("%w: attribute names and values must not be empty", ErrInvalidVariant)
+		}
+		variant.Attributes[name] = value
+	}
+	if len(variant.Attributes) == 0 {
+		return nil, fmt.Errorf("%w: at least one attribute is required", ErrInvalidVariant)
+	}
+	variant.AttributeKey = attributeKey(variant.Attributes)
+
+	if req.Price != nil {
+		if err := validatePrice(*req.Price); err != nil {
+			return nil, err
+		}
+		variant.PriceOverride = *req.Price
+	}
+	return variant, nil
+}
+
+// optionCombinations expands option values into every combination of one
+// value per option
+func optionCombinations(options map[string][]string) ([]map[string]string, error) {
+	combinations := []map[string]string{{}}
+	for _, name := range slices.Sorted(maps.Keys(options)) {
+		var values []string
+		seen := make(map[string]bool)
+		for _, value := range options[name] {
+			value = strings.TrimSpace(value)
+			if value != "" && !seen[strings.ToLower(value)] {
+				seen[strings.ToLower(value)] = true
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%w: option %q has no values", ErrInvalidVariant, name)
+		}
+		if len(combinations)*len(values) > maxGeneratedVariants {
+			return nil, fmt.Errorf("%w: more than %d combinations", ErrInvalidVariant, maxGeneratedVariants)
+		}
+
+		next := make([]map[string]string, 0, len(combinations)*len(values))
+		for _, combination := range combinations {
+			for _, value := range values {
+				attributes := maps.Clone(combination)
+				attributes[name] = value
+				next = append(next, attributes)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+// attributeKey identifies a combination of attribute values regardless of
+// order or case, e.g. "colour=red;size=m"
+func attributeKey(attributes map[string]string) string {
+	names := slices.Sorted(maps.Keys(attributes))
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strings.ToLower(attributes[name])
+	}
+	return strings.Join(pairs, ";")
+}
+
+// attributeSKU is the SKU suffix for a combination, e.g. "RED-M"
+func attributeSKU(attributes map[string]string) string {
+	names := slices.Sorted(maps.Keys(attributes))
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = strings.ToUpper(category.Slugify(attributes[name]))
+	}
+	return strings.Join(values, "-")
+}
+
+// productFromRequest validates and normalises a new product
+func (s *productService) productFromRequest(req CreateProductRequest) (*Product, error) {
+	if err := validatePrice(req.Price); err != nil {
+		return nil, err
+	}
+	if err := validateSchedule(req.PublishAt, req.UnpublishAt); err != nil {
+		return nil, err
+	}
+	categories, err := s.categories(req.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &Product{
+		SKU:         normalizeSKU(req.SKU),
+		Name:        req.Name,
+		Price:       req.Price,
+		Weight:      req.Weight,
+		Colour:      req.Colour,
+		Description: req.Description,
+		Stock:       req.Stock,
+		TaxClass:    normalizeTaxClass(req.TaxClass),
+		Categories:  categories,
+		Status:      req.Status,
+		PublishAt:   req.PublishAt,
+		UnpublishAt: req.UnpublishAt,
+	}
+	if product.TaxClass == "" {
+		product.TaxClass = DefaultTaxClass
+	}
+	if product.Status == "" {
+		product.Status = StatusDraft
+	}
+	return product, nil
+}
+
+// requestFromProduct describes a product as the request that would create it
+func requestFromProduct(p *Product) CreateProductRequest {
+	req := CreateProductRequest{
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Price:       p.Price,
+		Weight:      p.Weight,
+		Colour:      p.Colour,
+		Description: p.Description,
+		Stock:       p.Stock,
+		TaxClass:    p.TaxClass,
+		CategoryIDs: make([]int, len(p.Categories)),
+		Status:      p.Status,
+		PublishAt:   p.PublishAt,
+		UnpublishAt: p.UnpublishAt,
+	}
+	for i, c := range p.Categories {
+		req.CategoryIDs[i] = c.ID
+	}
+	return req
+}
+
+// validateSchedule rejects an unpublish time that is not after the publish time
+func validateSchedule(publishAt *time.Time, unpublishAt *time.Time) error {
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return fmt.Errorf("%w: unpublish_at must be after publish_at", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// checkSKU rejects a product SKU used by another product or by any
+// variant, so that every SKU on an order names one thing. id is the product
+// being updated, or 0.
+func (s *productService) checkSKU(sku string, id int) error {
+	if sku == "" {
+		return nil
+	}
+	products, err := s.repo.FindBySKUs([]string{sku})
+	if err != nil {
+		return err
+	}
+	if len(products) > 0 && products[0].ID != id {
+		if products[0].DeletedAt.Valid {
+			return fmt.Errorf("%w: %s, by product %d in the trash", ErrSKUTaken, sku, products[0].ID)
+		}
+		return fmt.Errorf("%w: %s", ErrSKUTaken, sku)
+	}
+	variants, err := s.repo.FindVariantsBySKU([]string{sku})
+	if err != nil {
+		return err
+	}
+	if len(variants) > 0 {
+		return fmt.Errorf("%w: %s", ErrSKUTaken, sku)
+	}
+	return nil
+}
+
+// categories loads the categories with the given IDs, failing if any is missing
+func (s *productService) categories(ids []int) ([]category.Category, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	categories, err := s.categoryRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) != len(ids) {
+		return nil, fmt.Errorf("%w: one or more categories do not exist", ErrInvalidCategory)
+	}
+	return categories, nil
+}
+
+// normalizeSKU makes SKUs case-insensitive
+func normalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+// validatePrice accepts positive prices in the store currency, so that
+// carts and orders never mix currencies
+func validatePrice(price money.Money) error {
+	if !price.IsPositive() {
+		return fmt.Errorf("%w: must be greater than 0", ErrInvalidPrice)
+	}
+	if price.Currency != money.DefaultCurrency() {
+		return fmt.Errorf("%w: must be in %s", ErrInvalidPrice, money.DefaultCurrency())
+	}
+	return nil
+}
+
+// normalizeFilter applies listing defaults and rejects bounds the
+// repository cannot compare against
+func normalizeFilter(filter *ProductFilter) error {
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Colour = strings.TrimSpace(filter.Colour)
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = SortCreatedAt
+	case SortCreatedAt, SortName, SortPrice:
+	default:
+		return fmt.Errorf("%w: sort must be one of price, name, created_at", ErrInvalidFilter)
+	}
+
+	for _, bound := range []*money.Money{filter.MinPrice, filter.MaxPrice} {
+		if bound != nil && bound.Currency != money.DefaultCurrency() {
+			return fmt.Errorf("%w: prices are in %s", ErrInvalidFilter, money.DefaultCurrency())
+		}
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.Cmp(*filter.MaxPrice) > 0 {
+		return fmt.Errorf("%w: min_price is greater than max_price", ErrInvalidFilter)
+	}
+	if filter.MinWeight != nil && filter.MaxWeight != nil && *filter.MinWeight > *filter.MaxWeight {
+		return fmt.Errorf("%w: min_weight is greater than max_weight", ErrInvalidFilter)
+	}
+	switch filter.Status {
+	case "", StatusDraft, StatusPublished, StatusArchived:
+	default:
+		return fmt.Errorf("%w: status must be one of draft, published, archived", ErrInvalidFilter)
+	}
+
+	return normalizePage(&filter.Limit, &filter.Offset, DefaultPageLimit, MaxPageLimit)
+}
+
+// normalizePage defaults an unset limit and rejects out-of-range paging
+func normalizePage(limit *int, offset *int, defaultLimit int, maxLimit int) error {
+	switch {
+	case *limit == 0:
+		*limit = defaultLimit
+	case *limit < 0 || *limit > maxLimit:
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxLimit)
+	}
+	if *offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidFilter)
+	}
+	return nil
+}
+
+// prefixQuery turns typed text into a to_tsquery expression matching words
+// that start with each typed word, e.g. "Red app" becomes "red:* & app:*".
+// Punctuation is dropped so the result is always valid tsquery syntax.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,154 @@
+package product
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestOptionCombinations(t *testing.T) {
+	tooMany := make([]string, maxGeneratedVariants+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i)
+	}
+
+	tests := []struct {
+		name    string
+		options map[string][]string
+		want    []map[string]string
+		wantErr error
+	}{
+		{
+			name:    "no options",
+			options: map[string][]string{},
+			want:    []map[string]string{{}},
+		},
+		{
+			name:    "one option",
+			options: map[string][]string{"size": {"S", "M"}},
+			want:    []map[string]string{{"size": "S"}, {"size": "M"}},
+		},
+		{
+			name:    "options in name order",
+			options: map[string][]string{"size": {"S", "M"}, "colour": {"red", "blue"}},
+			want: []map[string]string{
+				{"colour": "red", "size": "S"},
+				{"colour": "red", "size": "M"},
+				{"colour": "blue", "size": "S"},
+				{"colour": "blue", "size": "M"},
+			},
+		},
+		{
+			name:    "trims and drops blank and repeated values",
+			options: map[string][]string{"size": {" S ", "", "s", "M", "  "}},
+			want:    []map[string]string{{"size": "S"}, {"size": "M"}},
+		},
+		{
+			name:    "option without values",
+			options: map[string][]string{"size": {"S"}, "colour": {" ", ""}},
+			wantErr: ErrInvalidVariant,
+		},
+		{
+			name:    "too many combinations",
+			options: map[string][]string{"size": tooMany},
+			wantErr: ErrInvalidVariant,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := optionCombinations(tt.options)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("optionCombinations() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("optionCombinations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("optionCombinations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeProductRepository serves a single product
+type fakeProductRepository struct {
+	ProductRepository
+	product *Product
+	created []Variant
+}
+
+func (r *fakeProductRepository) FindByID(id int) (*Product, error) {
+	if r.product == nil || r.product.ID != id {
+		return nil, errors.New("record not found")
+	}
+	return r.product, nil
+}
+
+func (r *fakeProductRepository) FindVariantsBySKU(skus []string) ([]Variant, error) {
+	return nil, nil
+}
+
+func (r *fakeProductRepository) FindBySKUs(skus []string) ([]Product, error) {
+	return nil, nil
+}
+
+func (r *fakeProductRepository) CreateVariants(variants []Variant) error {
+	r.created = append(r.created, variants...)
+	return nil
+}
+
+func TestGenerateVariantsSKUs(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  map[string][]string
+		wantSKUs []string
+		wantErr  error
+	}{
+		{
+			name:     "distinct values",
+			options:  map[string][]string{"size": {"S", "X-L"}},
+			wantSKUs: []string{"TEE-S", "TEE-X-L"},
+		},
+		{
+			name:    "values that slug the same",
+			options: map[string][]string{"size": {"X-L", "X L"}},
+			wantErr: ErrInvalidVariant,
+		},
+		{
+			name:    "clash across options",
+			options: map[string][]string{"colour": {"red", "red-x"}, "size": {"x-l", "l"}},
+			wantErr: ErrInvalidVariant,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeProductRepository{product: &Product{ID: 1, Name: "Tee"}}
+			service := NewProductService(repo, nil, nil, nil)
+
+			_, err := service.GenerateVariants(1, GenerateVariantsRequest{Options: tt.options})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GenerateVariants() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(repo.created) > 0 {
+					t.Errorf("GenerateVariants() created %d variants after failing", len(repo.created))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateVariants() error = %v", err)
+			}
+			var skus []string
+			for _, v := range repo.created {
+				skus = append(skus, v.SKU)
+			}
+			if !reflect.DeepEqual(skus, tt.wantSKUs) {
+				t.Errorf("GenerateVariants() SKUs = %v, want %v", skus, tt.wantSKUs)
+			}
+		})
+	}
+}
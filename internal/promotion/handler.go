@@ -0,0 +1,110 @@
+package promotion
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionHandler struct {
+	service PromotionService
+}
+
+func NewPromotionHandler(service PromotionService) *PromotionHandler {
+	return &PromotionHandler{service: service}
+}
+
+// CreateCoupon creates a new coupon (admin only)
+func (h *PromotionHandler) CreateCoupon(c *gin.Context) {
+	var req CouponRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	coupon, err := h.service.CreateCoupon(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// GetAllCoupons retrieves all coupons (admin only)
+func (h *PromotionHandler) GetAllCoupons(c *gin.Context) {
+	coupons, err := h.service.GetAllCoupons()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coupons"})
+		return
+	}
+
+	if len(coupons) == 0 {
+		c.JSON(http.StatusOK, []Coupon{})
+		return
+	}
+
+	c.JSON(http.StatusOK, coupons)
+}
+
+// GetCouponByID retrieves a coupon (admin only)
+func (h *PromotionHandler) GetCouponByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	coupon, err := h.service.GetCouponByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// UpdateCoupon replaces a coupon's settings (admin only)
+func (h *PromotionHandler) UpdateCoupon(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	var req CouponRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	coupon, err := h.service.UpdateCoupon(id, req)
+	if errors.Is(err, ErrCouponNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// DeleteCoupon deletes a coupon (admin only)
+func (h *PromotionHandler) DeleteCoupon(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	if err := h.service.DeleteCoupon(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+}
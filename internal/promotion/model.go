@@ -0,0 +1,75 @@
+package promotion
+
+import (
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Coupon types
+const (
+	TypePercentage = "percentage" // Value is a percentage off the eligible amount
+	TypeFixed      = "fixed"      // Amount is taken off the eligible amount
+)
+
+type Coupon struct {
+	ID           int             `json:"id" gorm:"primaryKey"`
+	Code         string          `json:"code" gorm:"uniqueIndex"`
+	Type         string          `json:"type"`
+	Value        float64         `json:"value,omitempty"`                                     // percent, for percentage coupons
+	Amount       money.Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`       // for fixed coupons
+	MinSpend     money.Money     `json:"min_spend" gorm:"embedded;embeddedPrefix:min_spend_"` // minimum order subtotal
+	MaxUses      int             `json:"max_uses"`                                            // across all users, 0 = unlimited
+	PerUserLimit int             `json:"per_user_limit"`                                      // 0 = unlimited
+	UsedCount    int             `json:"used_count"`
+	StartsAt     *time.Time      `json:"starts_at"`
+	EndsAt       *time.Time      `json:"ends_at"`
+	Active       bool            `json:"active"`
+	Products     []CouponProduct `json:"products" gorm:"foreignKey:CouponID"` // empty applies to every product
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// CouponProduct restricts a coupon to a product
+type CouponProduct struct {
+	ID        int `json:"-" gorm:"primaryKey"`
+	CouponID  int `json:"-" gorm:"index"`
+	ProductID int `json:"product_id"`
+}
+
+// CouponRedemption records a coupon used on an order
+type CouponRedemption struct {
+	ID        int         `json:"id" gorm:"primaryKey"`
+	CouponID  int         `json:"coupon_id" gorm:"index"`
+	UserID    int         `json:"user_id" gorm:"index"`
+	OrderID   int         `json:"order_id" gorm:"index"`
+	Discount  money.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type CouponRequest struct {
+	Code         string      `json:"code" binding:"required"`
+	Type         string      `json:"type" binding:"required,oneof=percentage fixed"`
+	Value        float64     `json:"value" binding:"gte=0,lte=100"` // percentage coupons
+	Amount       money.Money `json:"amount"`                        // fixed coupons
+	MinSpend     money.Money `json:"min_spend"`
+	MaxUses      int         `json:"max_uses" binding:"gte=0"`
+	PerUserLimit int         `json:"per_user_limit" binding:"gte=0"`
+	StartsAt     *time.Time  `json:"starts_at"`
+	EndsAt       *time.Time  `json:"ends_at"`
+	Active       *bool       `json:"active"` // defaults to true
+	ProductIDs   []int       `json:"product_ids" binding:"omitempty,dive,gt=0"`
+}
+
+// Line is an order line being priced
+type Line struct {
+	ProductID int
+	Amount    money.Money
+}
+
+// Quote is the discount a coupon gives on a set of lines
+type Quote struct {
+	Coupon        *Coupon
+	Discount      money.Money
+	LineDiscounts []money.Money // same order as the lines that were quoted
+}
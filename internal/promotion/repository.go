@@ -0,0 +1,159 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mini-ecommerce/pkg/money"
+)
+
+type CouponRepository interface {
+	Create(coupon *Coupon) error
+	FindAll() ([]Coupon, error)
+	FindByID(id int) (*Coupon, error)
+	FindByCode(code string) (*Coupon, error)
+	Update(id int, coupon *Coupon) error
+	Delete(id int) error
+	CountUserRedemptions(couponID int, userID int) (int64, error)
+	Redeem(couponID int, userID int, orderID int, discount money.Money) error
+	Release(orderID int) error
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{db: db}
+}
+
+func (r *couponRepository) Create(coupon *Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+func (r *couponRepository) FindAll() ([]Coupon, error) {
+	var coupons []Coupon
+	err := r.db.Preload("Products").Order("id").Find(&coupons).Error
+	return coupons, err
+}
+
+func (r *couponRepository) FindByID(id int) (*Coupon, error) {
+	var coupon Coupon
+	err := r.db.Preload("Products").First(&coupon, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) FindByCode(code string) (*Coupon, error) {
+	var coupon Coupon
+	err := r.db.Preload("Products").Where("code = ?", code).First(&coupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// Update saves every coupon field, including zero values, and replaces its product rules
+func (r *couponRepository) Update(id int, coupon *Coupon) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Coupon{}).Where("id = ?", id).
+			Select("*").Omit("id", "used_count", "created_at", "Products").
+			Updates(coupon).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("coupon_id = ?", id).Delete(&CouponProduct{}).Error; err != nil {
+			return err
+		}
+		for i := range coupon.Products {
+			coupon.Products[i].CouponID = id
+		}
+		if len(coupon.Products) == 0 {
+			return nil
+		}
+		return tx.Create(&coupon.Products).Error
+	})
+}
+
+func (r *couponRepository) Delete(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("coupon_id = ?", id).Delete(&CouponProduct{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Coupon{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *couponRepository) CountUserRedemptions(couponID int, userID int) (int64, error) {
+	var count int64
+	err := r.db.Model(&CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", couponID, userID).Count(&count).Error
+	return count, err
+}
+
+// Redeem records a use of the coupon. The coupon row is locked so usage
+// limits hold when several orders use the same coupon at once; call it on a
+// repository created with the order's transaction.
+func (r *couponRepository) Redeem(couponID int, userID int, orderID int, discount money.Money) error {
+	var coupon Coupon
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, couponID).Error
+	if err != nil {
+		return err
+	}
+
+	if coupon.MaxUses > 0 && coupon.UsedCount >= coupon.MaxUses {
+		return fmt.Errorf("%w: usage limit reached", ErrInvalidCoupon)
+	}
+	if coupon.PerUserLimit > 0 {
+		used, err := r.CountUserRedemptions(couponID, userID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return fmt.Errorf("%w: you have already used this coupon", ErrInvalidCoupon)
+		}
+	}
+
+	err = r.db.Model(&Coupon{}).Where("id = ?", couponID).
+		UpdateColumn("used_count", gorm.Expr("used_count + 1")).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Create(&CouponRedemption{
+		CouponID: couponID,
+		UserID:   userID,
+		OrderID:  orderID,
+		Discount: discount,
+	}).Error
+}
+
+// Release gives back the coupon use recorded against an order, so that it
+// counts towards neither the coupon's nor the customer's limit. Call it on a
+// repository created with the transaction that cancels the order.
+func (r *couponRepository) Release(orderID int) error {
+	var redemption CouponRedemption
+	err := r.db.Where("order_id = ?", orderID).Take(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := r.db.Delete(&redemption).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&Coupon{}).Where("id = ? AND used_count > 0", redemption.CouponID).
+		UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error
+}
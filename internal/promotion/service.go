@@ -0,0 +1,234 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"mini-ecommerce/pkg/money"
+)
+
+var (
+	// ErrInvalidCoupon wraps every reason a coupon cannot be applied
+	ErrInvalidCoupon  = errors.New("invalid coupon")
+	ErrCouponNotFound = errors.New("coupon not found")
+)
+
+type PromotionService interface {
+	CreateCoupon(req CouponRequest) (*Coupon, error)
+	GetAllCoupons() ([]Coupon, error)
+	GetCouponByID(id int) (*Coupon, error)
+	UpdateCoupon(id int, req CouponRequest) (*Coupon, error)
+	DeleteCoupon(id int) error
+	Quote(code string, userID int, lines []Line) (*Quote, error)
+	Redeem(tx *gorm.DB, quote *Quote, userID int, orderID int) error
+}
+
+type promotionService struct {
+	repo CouponRepository
+}
+
+func NewPromotionService(repo CouponRepository) PromotionService {
+	return &promotionService{repo: repo}
+}
+
+func (s *promotionService) CreateCoupon(req CouponRequest) (*Coupon, error) {
+	coupon, err := couponFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if existing, _ := s.repo.FindByCode(coupon.Code); existing != nil {
+		return nil, errors.New("coupon code already exists")
+	}
+
+	err = s.repo.Create(coupon)
+	if err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+func (s *promotionService) GetAllCoupons() ([]Coupon, error) {
+	return s.repo.FindAll()
+}
+
+func (s *promotionService) GetCouponByID(id int) (*Coupon, error) {
+	coupon, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrCouponNotFound
+	}
+	return coupon, nil
+}
+
+func (s *promotionService) UpdateCoupon(id int, req CouponRequest) (*Coupon, error) {
+	if _, err := s.GetCouponByID(id); err != nil {
+		return nil, err
+	}
+
+	coupon, err := couponFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if existing, _ := s.repo.FindByCode(coupon.Code); existing != nil && existing.ID != id {
+		return nil, errors.New("coupon code already exists")
+	}
+
+	err = s.repo.Update(id, coupon)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+func (s *promotionService) DeleteCoupon(id int) error {
+	if err := s.repo.Delete(id); err != nil {
+		return ErrCouponNotFound
+	}
+	return nil
+}
+
+// Quote checks that the coupon can be used by the user on these lines and
+// works out the discount. The discount is spread over the eligible lines in
+// proportion to their amounts.
+func (s *promotionService) Quote(code string, userID int, lines []Line) (*Quote, error) {
+	coupon, err := s.repo.FindByCode(normalizeCode(code))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s does not exist", ErrInvalidCoupon, code)
+	}
+
+	now := time.Now()
+	switch {
+	case !coupon.Active:
+		return nil, fmt.Errorf("%w: coupon is not active", ErrInvalidCoupon)
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return nil, fmt.Errorf("%w: coupon is not valid yet", ErrInvalidCoupon)
+	case coupon.EndsAt != nil && !now.Before(*coupon.EndsAt):
+		return nil, fmt.Errorf("%w: coupon has expired", ErrInvalidCoupon)
+	case coupon.MaxUses > 0 && coupon.UsedCount >= coupon.MaxUses:
+		return nil, fmt.Errorf("%w: usage limit reached", ErrInvalidCoupon)
+	}
+
+	if coupon.PerUserLimit > 0 {
+		used, err := s.repo.CountUserRedemptions(coupon.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return nil, fmt.Errorf("%w: you have already used this coupon", ErrInvalidCoupon)
+		}
+	}
+
+	subtotal := money.Zero(coupon.MinSpend.Currency)
+	eligible := subtotal
+	for _, line := range lines {
+		if !line.Amount.SameCurrency(subtotal) {
+			return nil, fmt.Errorf("%w: coupon is only valid for %s orders", ErrInvalidCoupon, subtotal.Currency)
+		}
+		subtotal = subtotal.Add(line.Amount)
+		if coupon.appliesTo(line.ProductID) {
+			eligible = eligible.Add(line.Amount)
+		}
+	}
+	if subtotal.Cmp(coupon.MinSpend) < 0 {
+		return nil, fmt.Errorf("%w: minimum spend is %s", ErrInvalidCoupon, coupon.MinSpend)
+	}
+	if eligible.IsZero() {
+		return nil, fmt.Errorf("%w: coupon does not apply to these products", ErrInvalidCoupon)
+	}
+
+	discount := coupon.Amount
+	if coupon.Type == TypePercentage {
+		discount = eligible.Percent(coupon.Value)
+	}
+	discount = money.Min(discount, eligible)
+
+	quote := &Quote{
+		Coupon:        coupon,
+		Discount:      discount,
+		LineDiscounts: make([]money.Money, len(lines)),
+	}
+
+	// Spread the discount; the last eligible line takes the rounding remainder
+	remaining := discount
+	last := -1
+	for i, line := range lines {
+		quote.LineDiscounts[i] = money.Zero(discount.Currency)
+		if !coupon.appliesTo(line.ProductID) {
+			continue
+		}
+		share := discount.MulFrac(line.Amount.Amount, eligible.Amount)
+		quote.LineDiscounts[i] = share
+		remaining = remaining.Sub(share)
+		last = i
+	}
+	quote.LineDiscounts[last] = quote.LineDiscounts[last].Add(remaining)
+
+	return quote, nil
+}
+
+// Redeem records the quoted coupon against an order inside the order's transaction
+func (s *promotionService) Redeem(tx *gorm.DB, quote *Quote, userID int, orderID int) error {
+	return NewCouponRepository(tx).Redeem(quote.Coupon.ID, userID, orderID, quote.Discount)
+}
+
+func (c *Coupon) appliesTo(productID int) bool {
+	if len(c.Products) == 0 {
+		return true
+	}
+	for _, p := range c.Products {
+		if p.ProductID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+func couponFromRequest(req CouponRequest) (*Coupon, error) {
+	amount := req.Amount.OrDefault()
+	minSpend := req.MinSpend.OrDefault()
+	switch req.Type {
+	case TypePercentage:
+		if req.Value <= 0 {
+			return nil, errors.New("percentage coupons need a value between 0 and 100")
+		}
+		amount = money.Zero(money.DefaultCurrency())
+	case TypeFixed:
+		if !amount.IsPositive() {
+			return nil, errors.New("fixed coupons need a positive amount")
+		}
+		req.Value = 0
+	}
+	for _, m := range []money.Money{amount, minSpend} {
+		if m.IsNegative() || m.Currency != money.DefaultCurrency() {
+			return nil, fmt.Errorf("amounts must be zero or more in %s", money.DefaultCurrency())
+		}
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+
+	coupon := &Coupon{
+		Code:         normalizeCode(req.Code),
+		Type:         req.Type,
+		Value:        req.Value,
+		Amount:       amount,
+		MinSpend:     minSpend,
+		MaxUses:      req.MaxUses,
+		PerUserLimit: req.PerUserLimit,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		Active:       req.Active == nil || *req.Active,
+	}
+	for _, productID := range req.ProductIDs {
+		coupon.Products = append(coupon.Products, CouponProduct{ProductID: productID})
+	}
+	return coupon, nil
+}
+
+// normalizeCode makes coupon codes case-insensitive
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,177 @@
+package returns
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReturnHandler struct {
+	service      ReturnService
+	orderService order.OrderService
+}
+
+func NewReturnHandler(service ReturnService, orderService order.OrderService) *ReturnHandler {
+	return &ReturnHandler{
+		service:      service,
+		orderService: orderService,
+	}
+}
+
+// CreateReturn opens a return request against one of the caller's delivered orders
+func (h *ReturnHandler) CreateReturn(c *gin.Context) {
+	var req CreateReturnRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ord, err := h.orderService.GetOrderByID(req.OrderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if !middleware.IsOwnerOrAdmin(c, ord.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this order"})
+		return
+	}
+
+	ret, err := h.service.CreateReturn(ord, req)
+	if errors.Is(err, ErrOrderNotReturnable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+// GetMyReturns lists the logged-in user's returns
+func (h *ReturnHandler) GetMyReturns(c *gin.Context) {
+	rets, err := h.service.GetReturns(ReturnFilter{UserID: c.GetInt("userID")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch returns"})
+		return
+	}
+
+	if len(rets) == 0 {
+		c.JSON(http.StatusOK, []ReturnRequest{})
+		return
+	}
+
+	c.JSON(http.StatusOK, rets)
+}
+
+// GetReturnByID retrieves a return (its owner or an admin)
+func (h *ReturnHandler) GetReturnByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return
+	}
+
+	ret, err := h.service.GetReturnByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return
+	}
+	if !middleware.IsOwnerOrAdmin(c, ret.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this return"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// GetReturns lists returns filtered by status, order_id, user_id or open=true (admin only)
+func (h *ReturnHandler) GetReturns(c *gin.Context) {
+	var filter ReturnFilter
+	var err error
+
+	filter.Status = c.Query("status")
+	if v := c.Query("order_id"); v != "" {
+		if filter.OrderID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+			return
+		}
+	}
+	if v := c.Query("user_id"); v != "" {
+		if filter.UserID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+	}
+	if filter.OpenOnly, err = strconv.ParseBool(c.DefaultQuery("open", "false")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid open value"})
+		return
+	}
+
+	rets, err := h.service.GetReturns(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch returns"})
+		return
+	}
+
+	if len(rets) == 0 {
+		c.JSON(http.StatusOK, []ReturnRequest{})
+		return
+	}
+
+	c.JSON(http.StatusOK, rets)
+}
+
+// ApproveReturn approves a return, refunding and restocking its items (admin only)
+func (h *ReturnHandler) ApproveReturn(c *gin.Context) {
+	h.review(c, func(id int, req ReviewReturnRequest) (*ReturnRequest, error) {
+		return h.service.ApproveReturn(id, req, order.ActorFromContext(c))
+	})
+}
+
+// RejectReturn rejects a return (admin only)
+func (h *ReturnHandler) RejectReturn(c *gin.Context) {
+	h.review(c, h.service.RejectReturn)
+}
+
+// ReceiveReturn records that returned goods arrived and pays the refund (admin only)
+func (h *ReturnHandler) ReceiveReturn(c *gin.Context) {
+	h.review(c, func(id int, req ReviewReturnRequest) (*ReturnRequest, error) {
+		return h.service.ReceiveReturn(id, req, order.ActorFromContext(c))
+	})
+}
+
+// review parses the return ID and optional note, runs the admin action and
+// writes the response
+func (h *ReturnHandler) review(c *gin.Context, action func(id int, req ReviewReturnRequest) (*ReturnRequest, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return
+	}
+
+	var req ReviewReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	ret, err := action(id, req)
+	switch {
+	case errors.Is(err, ErrReturnNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, ret)
+	}
+}
@@ -0,0 +1,85 @@
+package returns
+
+import (
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Return request statuses. A request is opened by the customer, then approved
+// or rejected by an admin; approved returns are received once the goods arrive.
+const (
+	StatusRequested = "requested"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusReceived  = "received"
+)
+
+// Refund statuses
+const (
+	RefundPending   = "pending"   // approved, waiting for the goods
+	RefundProcessed = "processed" // money returned through the payment gateway
+	RefundManual    = "manual"    // no gateway payment; refund handled outside the system
+)
+
+// ReturnRequest is a customer's request to send back items of a delivered order
+type ReturnRequest struct {
+	ID        int          `json:"id" gorm:"primaryKey"`
+	OrderID   int          `json:"order_id" gorm:"index"`
+	UserID    int          `json:"user_id" gorm:"index"`
+	Status    string       `json:"status" gorm:"index"`
+	Reason    string       `json:"reason"`
+	AdminNote string       `json:"admin_note,omitempty"`
+	Items     []ReturnItem `json:"items" gorm:"foreignKey:ReturnRequestID"`
+	Refund    *Refund      `json:"refund,omitempty" gorm:"foreignKey:ReturnRequestID"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// ReturnItem is a quantity of one order line being returned
+type ReturnItem struct {
+	ID              int         `json:"id" gorm:"primaryKey"`
+	ReturnRequestID int         `json:"return_request_id" gorm:"index"`
+	OrderItemID     int         `json:"order_item_id" gorm:"index"`
+	ProductID       int         `json:"product_id"`
+	VariantID       int         `json:"variant_id,omitempty"`
+	Quantity        int         `json:"quantity"`
+	Reason          string      `json:"reason"`
+	Amount          money.Money `json:"amount" gorm:"embedded"` // amount paid for the returned quantity
+}
+
+// Refund is the money owed for an approved return
+type Refund struct {
+	ID              int         `json:"id" gorm:"primaryKey"`
+	ReturnRequestID int         `json:"return_request_id" gorm:"uniqueIndex"`
+	OrderID         int         `json:"order_id" gorm:"index"`
+	Amount          money.Money `json:"amount" gorm:"embedded"`
+	Status          string      `json:"status"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+type ReturnItemRequest struct {
+	OrderItemID int    `json:"order_item_id" binding:"required,gt=0"`
+	Quantity    int    `json:"quantity" binding:"required,gt=0"`
+	Reason      string `json:"reason"`
+}
+
+type CreateReturnRequest struct {
+	OrderID int                 `json:"order_id" binding:"required,gt=0"`
+	Reason  string              `json:"reason" binding:"required"`
+	Items   []ReturnItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// ReviewReturnRequest carries the admin's note when approving, rejecting or receiving a return
+type ReviewReturnRequest struct {
+	Note string `json:"note"`
+}
+
+// ReturnFilter narrows the returns listed for admins
+type ReturnFilter struct {
+	Status   string
+	OrderID  int
+	UserID   int
+	OpenOnly bool // requested or approved
+}
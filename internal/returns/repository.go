@@ -0,0 +1,204 @@
+package returns
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/internal/product"
+)
+
+type ReturnRepository interface {
+	Create(ret *ReturnRequest, ord *order.Order) error
+	FindByID(id int) (*ReturnRequest, error)
+	FindAll(filter ReturnFilter) ([]ReturnRequest, error)
+	ReturnedQuantities(orderID int) (map[int]int, error)
+	Approve(id int, note string) error
+	UpdateStatus(id int, from string, to string, note string) error
+	UpdateRefundStatus(returnID int, status string) error
+}
+
+type returnRepository struct {
+	db *gorm.DB
+}
+
+func NewReturnRepository(db *gorm.DB) ReturnRepository {
+	return &returnRepository{db: db}
+}
+
+// Create inserts a return for ord and works out what each of its lines
+// refunds. The order row is locked while what was already returned is
+// re-read, so that two returns for the same lines can't both get through.
+func (r *returnRepository) Create(ret *ReturnRequest, ord *order.Order) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var locked order.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&locked, ord.ID).Error
+		if err != nil {
+			return err
+		}
+		if locked.Status != order.StatusDelivered && locked.Status != order.StatusReturned {
+			return ErrOrderNotReturnable
+		}
+
+		returned, err := returnedLines(tx, ord.ID)
+		if err != nil {
+			return err
+		}
+		for i := range ret.Items {
+			line := &ret.Items[i]
+			item := findOrderItem(ord, line.OrderItemID)
+			if item == nil {
+				return fmt.Errorf("order item %d is not part of order %d", line.OrderItemID, ord.ID)
+			}
+			before := returned[item.ID]
+			if before.Quantity+line.Quantity > item.Quantity {
+				return fmt.Errorf("%w: cannot return more than %d of %s", ErrTooManyReturned, item.Quantity, item.Name)
+			}
+			line.Amount = refundAmount(item, line.Quantity, before)
+			returned[item.ID] = returnedLine{
+				Quantity: before.Quantity + line.Quantity,
+				Amount:   before.Amount + line.Amount.Amount,
+			}
+		}
+
+		return tx.Create(ret).Error
+	})
+}
+
+func (r *returnRepository) FindByID(id int) (*ReturnRequest, error) {
+	var ret ReturnRequest
+	err := r.db.Preload("Items").Preload("Refund").First(&ret, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func (r *returnRepository) FindAll(filter ReturnFilter) ([]ReturnRequest, error) {
+	var rets []ReturnRequest
+	query := r.db.Preload("Items").Preload("Refund")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.OpenOnly {
+		query = query.Where("status IN ?", []string{StatusRequested, StatusApproved})
+	}
+	if filter.OrderID > 0 {
+		query = query.Where("order_id = ?", filter.OrderID)
+	}
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	err := query.Order("created_at DESC, id DESC").Find(&rets).Error
+	if err != nil {
+		return nil, err
+	}
+	return rets, nil
+}
+
+// ReturnedQuantities sums, per order item, the quantities on returns that
+// were not rejected
+func (r *returnRepository) ReturnedQuantities(orderID int) (map[int]int, error) {
+	lines, err := returnedLines(r.db, orderID)
+	if err != nil {
+		return nil, err
+	}
+	quantities := make(map[int]int, len(lines))
+	for id, line := range lines {
+		quantities[id] = line.Quantity
+	}
+	return quantities, nil
+}
+
+// returnedLine is how much of an order item is on returns that were not
+// rejected
+type returnedLine struct {
+	Quantity int
+	Amount   int64 // minor units, in the order item's currency
+}
+
+func returnedLines(db *gorm.DB, orderID int) (map[int]returnedLine, error) {
+	var rows []struct {
+		OrderItemID int
+		Quantity    int
+		Amount      int64
+	}
+	err := db.Model(&ReturnItem{}).
+		Select("return_items.order_item_id, SUM(return_items.quantity) AS quantity, SUM(return_items.amount) AS amount").
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests.order_id = ? AND return_requests.status <> ?", orderID, StatusRejected).
+		Group("return_items.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[int]returnedLine, len(rows))
+	for _, row := range rows {
+		lines[row.OrderItemID] = returnedLine{Quantity: row.Quantity, Amount: row.Amount}
+	}
+	return lines, nil
+}
+
+// Approve marks a requested return approved, creates its refund and puts the
+// returned quantities back in stock in a single transaction
+func (r *returnRepository) Approve(id int, note string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ret ReturnRequest
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&ret, id).Error
+		if err != nil {
+			return err
+		}
+		if ret.Status != StatusRequested {
+			return fmt.Errorf("%w: return is %s", ErrInvalidTransition, ret.Status)
+		}
+
+		refund := &Refund{
+			ReturnRequestID: ret.ID,
+			OrderID:         ret.OrderID,
+			Status:          RefundPending,
+		}
+		for _, item := range ret.Items {
+			refund.Amount = refund.Amount.Add(item.Amount)
+			// Products in the trash are restocked too, in case they are restored
+			restock := tx.Unscoped().Model(&product.Product{}).Where("id = ?", item.ProductID)
+			if item.VariantID > 0 {
+				restock = tx.Model(&product.Variant{}).Where("id = ?", item.VariantID)
+			}
+			err := restock.UpdateColumn("stock", gorm.Expr("stock + ?", item.Quantity)).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&ReturnRequest{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":     StatusApproved,
+			"admin_note": note,
+		}).Error
+	})
+}
+
+// UpdateStatus moves a return from one status to another, failing if it is
+// no longer in the expected status
+func (r *returnRepository) UpdateStatus(id int, from string, to string, note string) error {
+	result := r.db.Model(&ReturnRequest{}).Where("id = ? AND status = ?", id, from).Updates(map[string]interface{}{
+		"status":     to,
+		"admin_note": note,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: return is not %s", ErrInvalidTransition, from)
+	}
+	return nil
+}
+
+func (r *returnRepository) UpdateRefundStatus(returnID int, status string) error {
+	return r.db.Model(&Refund{}).Where("return_request_id = ?", returnID).Update("status", status).Error
+}
@@ -0,0 +1,214 @@
+package returns
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/internal/payment"
+	"mini-ecommerce/pkg/money"
+)
+
+var (
+	ErrReturnNotFound     = errors.New("return not found")
+	ErrInvalidTransition  = errors.New("invalid return status change")
+	ErrOrderNotReturnable = errors.New("only delivered orders can be returned")
+	ErrTooManyReturned    = errors.New("return quantity exceeds what was bought")
+)
+
+type ReturnService interface {
+	CreateReturn(ord *order.Order, req CreateReturnRequest) (*ReturnRequest, error)
+	GetReturnByID(id int) (*ReturnRequest, error)
+	GetReturns(filter ReturnFilter) ([]ReturnRequest, error)
+	ApproveReturn(id int, req ReviewReturnRequest, actor order.Actor) (*ReturnRequest, error)
+	RejectReturn(id int, req ReviewReturnRequest) (*ReturnRequest, error)
+	ReceiveReturn(id int, req ReviewReturnRequest, actor order.Actor) (*ReturnRequest, error)
+}
+
+type returnService struct {
+	repo           ReturnRepository
+	orderService   order.OrderService
+	paymentService payment.PaymentService
+}
+
+func NewReturnService(repo ReturnRepository, orderService order.OrderService, paymentService payment.PaymentService) ReturnService {
+	return &returnService{
+		repo:           repo,
+		orderService:   orderService,
+		paymentService: paymentService,
+	}
+}
+
+// CreateReturn opens a return for lines of a delivered order. Each line can
+// only be returned up to the quantity bought, across all open returns.
+func (s *returnService) CreateReturn(ord *order.Order, req CreateReturnRequest) (*ReturnRequest, error) {
+	// A partially returned order is already "returned" but may have lines left
+	if ord.Status != order.StatusDelivered && ord.Status != order.StatusReturned {
+		return nil, ErrOrderNotReturnable
+	}
+
+	ret := &ReturnRequest{
+		OrderID: ord.ID,
+		UserID:  ord.UserID,
+		Status:  StatusRequested,
+		Reason:  req.Reason,
+	}
+
+	for _, line := range req.Items {
+		item := findOrderItem(ord, line.OrderItemID)
+		if item == nil {
+			return nil, fmt.Errorf("order item %d is not part of order %d", line.OrderItemID, ord.ID)
+		}
+
+		// The amount is worked out when the return is saved
+		ret.Items = append(ret.Items, ReturnItem{
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			Quantity:    line.Quantity,
+			Reason:      line.Reason,
+		})
+	}
+
+	if err := s.repo.Create(ret, ord); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *returnService) GetReturnByID(id int) (*ReturnRequest, error) {
+	ret, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrReturnNotFound
+	}
+	return ret, nil
+}
+
+func (s *returnService) GetReturns(filter ReturnFilter) ([]ReturnRequest, error) {
+	return s.repo.FindAll(filter)
+}
+
+// ApproveReturn accepts a return: a refund is recorded, the items go back in
+// stock and a delivered order moves to returned
+func (s *returnService) ApproveReturn(id int, req ReviewReturnRequest, actor order.Actor) (*ReturnRequest, error) {
+	if _, err := s.GetReturnByID(id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Approve(id, req.Note); err != nil {
+		return nil, err
+	}
+
+	ret, err := s.GetReturnByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ord, err := s.orderService.GetOrderByID(ret.OrderID)
+	if err == nil && ord.Status == order.StatusDelivered {
+		_, err = s.orderService.UpdateOrderStatus(ord.ID, order.UpdateOrderStatusRequest{
+			Status: order.StatusReturned,
+			Reason: fmt.Sprintf("return #%d approved", ret.ID),
+		}, actor)
+	}
+	if err != nil {
+		log.Printf("return %d: order %d not marked returned: %v", ret.ID, ret.OrderID, err)
+	}
+
+	return ret, nil
+}
+
+func (s *returnService) RejectReturn(id int, req ReviewReturnRequest) (*ReturnRequest, error) {
+	if _, err := s.GetReturnByID(id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateStatus(id, StatusRequested, StatusRejected, req.Note); err != nil {
+		return nil, err
+	}
+	return s.GetReturnByID(id)
+}
+
+// ReceiveReturn records that the goods arrived and pays out the refund. If
+// the order was paid through the gateway the money is returned there,
+// otherwise the refund is left for manual handling.
+func (s *returnService) ReceiveReturn(id int, req ReviewReturnRequest, actor order.Actor) (*ReturnRequest, error) {
+	ret, err := s.GetReturnByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateStatus(id, StatusApproved, StatusReceived, req.Note); err != nil {
+		return nil, err
+	}
+
+	refundStatus := RefundProcessed
+	_, err = s.paymentService.RefundOrder(ret.OrderID, ret.Refund.Amount)
+	if errors.Is(err, payment.ErrNoCapturedPayment) {
+		refundStatus = RefundManual
+	} else if err != nil {
+		// Put the return back so the admin can retry
+		if revertErr := s.repo.UpdateStatus(id, StatusReceived, StatusApproved, ret.AdminNote); revertErr != nil {
+			log.Printf("return %d: failed to revert to approved: %v", id, revertErr)
+		}
+		return nil, fmt.Errorf("refund failed: %w", err)
+	}
+	if err := s.repo.UpdateRefundStatus(id, refundStatus); err != nil {
+		return nil, err
+	}
+
+	s.markOrderRefunded(ret.OrderID, actor)
+	return s.GetReturnByID(id)
+}
+
+// markOrderRefunded moves a returned order to refunded once every line has
+// been returned and no return is still open
+func (s *returnService) markOrderRefunded(orderID int, actor order.Actor) {
+	ord, err := s.orderService.GetOrderByID(orderID)
+	if err != nil || ord.Status != order.StatusReturned {
+		return
+	}
+
+	open, err := s.repo.FindAll(ReturnFilter{OrderID: orderID, OpenOnly: true})
+	if err != nil || len(open) > 0 {
+		return
+	}
+	returned, err := s.repo.ReturnedQuantities(orderID)
+	if err != nil {
+		return
+	}
+	for _, item := range ord.Items {
+		if returned[item.ID] < item.Quantity {
+			return
+		}
+	}
+
+	_, err = s.orderService.UpdateOrderStatus(orderID, order.UpdateOrderStatusRequest{
+		Status: order.StatusRefunded,
+		Reason: "all items returned and refunded",
+	}, actor)
+	if err != nil {
+		log.Printf("order %d not marked refunded: %v", orderID, err)
+	}
+}
+
+// refundAmount is what was paid for quantity units of the line, after its
+// share of any order discount and including its tax. returned is what is
+// already on other returns. The return that completes the line gets whatever
+// is left of what was paid for it, so rounding each return on its own never
+// adds up to more than was paid.
+func refundAmount(item *order.OrderItem, quantity int, returned returnedLine) money.Money {
+	paid := item.Total.Sub(item.Discount).Add(item.TaxAmount)
+	left := paid.Sub(money.New(returned.Amount, paid.Currency))
+	if returned.Quantity+quantity >= item.Quantity {
+		return left
+	}
+	return money.Min(paid.MulFrac(int64(quantity), int64(item.Quantity)), left)
+}
+
+func findOrderItem(ord *order.Order, itemID int) *order.OrderItem {
+	for i := range ord.Items {
+		if ord.Items[i].ID == itemID {
+			return &ord.Items[i]
+		}
+	}
+	return nil
+}
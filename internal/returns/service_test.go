@@ -0,0 +1,62 @@
+package returns
+
+import (
+	"testing"
+
+	"mini-ecommerce/internal/order"
+	"mini-ecommerce/pkg/money"
+)
+
+func TestRefundAmountNeverExceedsWhatWasPaid(t *testing.T) {
+	bdt := func(amount int64) money.Money { return money.New(amount, "BDT") }
+
+	tests := []struct {
+		name    string
+		item    order.OrderItem
+		returns []int // quantity of each return, in order
+		want    []int64
+	}{
+		{
+			name:    "rounding down on every return",
+			item:    order.OrderItem{Quantity: 3, Total: bdt(1000), Discount: bdt(0), TaxAmount: bdt(0)},
+			returns: []int{1, 1, 1},
+			want:    []int64{333, 333, 334},
+		},
+		{
+			// Each third of 10.01 rounds up to 3.34; three of them would be 10.02
+			name:    "rounding up on every return",
+			item:    order.OrderItem{Quantity: 3, Total: bdt(1001), Discount: bdt(0), TaxAmount: bdt(0)},
+			returns: []int{1, 1, 1},
+			want:    []int64{334, 334, 333},
+		},
+		{
+			name:    "with a discount",
+			item:    order.OrderItem{Quantity: 7, Total: bdt(1000), Discount: bdt(1), TaxAmount: bdt(150)},
+			returns: []int{2, 4, 1},
+			want:    []int64{328, 657, 164},
+		},
+		{
+			name:    "whole line at once",
+			item:    order.OrderItem{Quantity: 3, Total: bdt(1001), Discount: bdt(0), TaxAmount: bdt(0)},
+			returns: []int{3},
+			want:    []int64{1001},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paid := tt.item.Total.Sub(tt.item.Discount).Add(tt.item.TaxAmount)
+			var returned returnedLine
+			for i, quantity := range tt.returns {
+				got := refundAmount(&tt.item, quantity, returned)
+				if got.Amount != tt.want[i] || got.Currency != "BDT" {
+					t.Errorf("return %d of %d: refundAmount() = %v, want %d BDT", i+1, quantity, got, tt.want[i])
+				}
+				returned.Quantity += quantity
+				returned.Amount += got.Amount
+			}
+			if returned.Amount != paid.Amount {
+				t.Errorf("returns refunded %d in all, want the %d paid", returned.Amount, paid.Amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package review
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"mini-ecommerce/internal/product"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	service ReviewService
+}
+
+func NewReviewHandler(service ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+// CreateReview reviews a product the logged-in user has received. The
+// review is shown once an admin approves it.
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req CreateReviewRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	review, err := h.service.CreateReview(productID, c.GetInt("userID"), req)
+	if err != nil {
+		respondError(c, err, "Failed to create review")
+		return
+	}
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetProductReviews lists a product's approved reviews (accessible to
+// customers). It takes limit and offset.
+func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.GetProductReviews(productID, limit, offset)
+	if err != nil {
+		respondError(c, err, "Failed to fetch reviews")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetMyReviews lists the logged-in user's reviews in every status
+func (h *ReviewHandler) GetMyReviews(c *gin.Context) {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.GetReviews(ReviewFilter{UserID: c.GetInt("userID"), Limit: limit, Offset: offset})
+	if err != nil {
+		respondError(c, err, "Failed to fetch reviews")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetReviews is the moderation queue (admin only). It lists pending reviews,
+// oldest first, unless given another status; it also takes product_id,
+// limit and offset.
+func (h *ReviewHandler) GetReviews(c *gin.Context) {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := ReviewFilter{
+		Status:      c.DefaultQuery("status", StatusPending),
+		OldestFirst: true,
+		Limit:       limit,
+		Offset:      offset,
+	}
+	if v := c.Query("product_id"); v != "" {
+		if filter.ProductID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+			return
+		}
+	}
+
+	page, err := h.service.GetReviews(filter)
+	if err != nil {
+		respondError(c, err, "Failed to fetch reviews")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ApproveReview publishes a review (admin only)
+func (h *ReviewHandler) ApproveReview(c *gin.Context) {
+	h.moderate(c, StatusApproved)
+}
+
+// RejectReview turns down a review (admin only)
+func (h *ReviewHandler) RejectReview(c *gin.Context) {
+	h.moderate(c, StatusRejected)
+}
+
+// HideReview takes down an approved review (admin only)
+func (h *ReviewHandler) HideReview(c *gin.Context) {
+	h.moderate(c, StatusHidden)
+}
+
+// moderate parses the review ID and optional note, and moves the review to status
+func (h *ReviewHandler) moderate(c *gin.Context, status string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	review, err := h.service.ModerateReview(id, status, req)
+	if err != nil {
+		respondError(c, err, "Failed to update review")
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// respondError maps review errors to status codes
+func respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, product.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case errors.Is(err, ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+	case errors.Is(err, ErrNotEligible):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadyReviewed), errors.Is(err, ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// pageParams reads the optional limit and offset query parameters
+func pageParams(c *gin.Context) (int, int, error) {
+	var limit, offset int
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return 0, 0, errors.New("invalid limit")
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			return 0, 0, errors.New("invalid offset")
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,77 @@
+package review
+
+import "time"
+
+// Review statuses. New reviews wait in the moderation queue, and only
+// approved ones are shown to customers or counted in product ratings.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusHidden   = "hidden" // taken down after being approved
+)
+
+// transitions lists the statuses a moderator can move a review to from each status
+var transitions = map[string][]string{
+	StatusPending:  {StatusApproved, StatusRejected},
+	StatusApproved: {StatusHidden},
+	StatusRejected: {StatusApproved},
+	StatusHidden:   {StatusApproved},
+}
+
+// Listing defaults and limits for reviews
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Review is a customer's rating of a product they received. Each customer
+// can review a product once.
+type Review struct {
+	ID             int       `json:"id" gorm:"primaryKey"`
+	ProductID      int       `json:"product_id" gorm:"not null;uniqueIndex:idx_reviews_product_user"`
+	UserID         int       `json:"user_id" gorm:"not null;uniqueIndex:idx_reviews_product_user;index"`
+	Rating         int       `json:"rating" gorm:"not null"` // 1 to 5 stars
+	Title          string    `json:"title" gorm:"not null"`
+	Body           string    `json:"body"`
+	Status         string    `json:"status" gorm:"not null;default:'pending';index"`
+	ModerationNote string    `json:"moderation_note,omitempty"` // shown to the author and admins only
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreateReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"required,max=200"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+// ModerateReviewRequest carries the admin's note when approving, rejecting
+// or hiding a review
+type ModerateReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// ReviewFilter narrows and pages the reviews listed. Zero values are not applied.
+type ReviewFilter struct {
+	ProductID   int
+	UserID      int
+	Status      string
+	OldestFirst bool // for working through the moderation queue
+	Limit       int
+	Offset      int
+}
+
+// ReviewPage is one page of reviews
+type ReviewPage struct {
+	Data   []Review `json:"data"`
+	Total  int64    `json:"total"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
+
+// IsValidStatus reports whether status is a known review status
+func IsValidStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
@@ -0,0 +1,120 @@
+package review
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"mini-ecommerce/internal/order"
+)
+
+type ReviewRepository interface {
+	Create(review *Review) error
+	FindByID(id int) (*Review, error)
+	FindByProductAndUser(productID int, userID int) (*Review, error)
+	FindAll(filter ReviewFilter) ([]Review, int64, error)
+	HasDeliveredOrder(userID int, productID int) (bool, error)
+	UpdateStatus(id int, from string, to string, note string) error
+	RatingSummary(productID int) (float64, int, error)
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+func (r *reviewRepository) Create(review *Review) error {
+	return r.db.Create(review).Error
+}
+
+func (r *reviewRepository) FindByID(id int) (*Review, error) {
+	var review Review
+	err := r.db.First(&review, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *reviewRepository) FindByProductAndUser(productID int, userID int) (*Review, error) {
+	var review Review
+	err := r.db.Where("product_id = ? AND user_id = ?", productID, userID).First(&review).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// FindAll returns one page of reviews matching filter, newest first unless
+// filter.OldestFirst, along with the number of matches across all pages
+func (r *reviewRepository) FindAll(filter ReviewFilter) ([]Review, int64, error) {
+	query := r.db.Model(&Review{})
+	if filter.ProductID > 0 {
+		query = query.Where("product_id = ?", filter.ProductID)
+	}
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "created_at DESC, id DESC"
+	if filter.OldestFirst {
+		order = "created_at, id"
+	}
+	var reviews []Review
+	err := query.Order(order).Limit(filter.Limit).Offset(filter.Offset).Find(&reviews).Error
+	return reviews, total, err
+}
+
+// HasDeliveredOrder reports whether the user has a delivered order that
+// includes the product. Orders returned since were delivered too.
+func (r *reviewRepository) HasDeliveredOrder(userID int, productID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&order.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status IN ? AND order_items.product_id = ?",
+			userID, []string{order.StatusDelivered, order.StatusReturned}, productID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateStatus moves a review from one status to another, failing if it is
+// no longer in the expected status
+func (r *reviewRepository) UpdateStatus(id int, from string, to string, note string) error {
+	result := r.db.Model(&Review{}).Where("id = ? AND status = ?", id, from).Updates(map[string]interface{}{
+		"status":          to,
+		"moderation_note": note,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: review is not %s", ErrInvalidTransition, from)
+	}
+	return nil
+}
+
+// RatingSummary returns the average rating and number of a product's
+// approved reviews
+func (r *reviewRepository) RatingSummary(productID int) (float64, int, error) {
+	var summary struct {
+		Average float64
+		Count   int
+	}
+	err := r.db.Model(&Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, StatusApproved).
+		Scan(&summary).Error
+	return summary.Average, summary.Count, err
+}
@@ -0,0 +1,132 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"mini-ecommerce/internal/product"
+)
+
+var (
+	ErrReviewNotFound    = errors.New("review not found")
+	ErrAlreadyReviewed   = errors.New("you have already reviewed this product")
+	ErrNotEligible       = errors.New("only customers who received this product can review it")
+	ErrInvalidTransition = errors.New("invalid review status change")
+	ErrInvalidFilter     = errors.New("invalid filter")
+)
+
+type ReviewService interface {
+	CreateReview(productID int, userID int, req CreateReviewRequest) (*Review, error)
+	GetProductReviews(productID int, limit int, offset int) (*ReviewPage, error)
+	GetReviews(filter ReviewFilter) (*ReviewPage, error)
+	ModerateReview(id int, status string, req ModerateReviewRequest) (*Review, error)
+}
+
+type reviewService struct {
+	repo        ReviewRepository
+	productRepo product.ProductRepository
+}
+
+func NewReviewService(repo ReviewRepository, productRepo product.ProductRepository) ReviewService {
+	return &reviewService{repo: repo, productRepo: productRepo}
+}
+
+// CreateReview adds a review to the moderation queue. The user must have
+// received the product in a delivered order, and may review it only once.
+func (s *reviewService) CreateReview(productID int, userID int, req CreateReviewRequest) (*Review, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, product.ErrProductNotFound
+	}
+	if existing, _ := s.repo.FindByProductAndUser(productID, userID); existing != nil {
+		return nil, ErrAlreadyReviewed
+	}
+	delivered, err := s.repo.HasDeliveredOrder(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !delivered {
+		return nil, ErrNotEligible
+	}
+
+	review := &Review{
+		ProductID: productID,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Title:     strings.TrimSpace(req.Title),
+		Body:      strings.TrimSpace(req.Body),
+		Status:    StatusPending,
+	}
+	if err := s.repo.Create(review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// GetProductReviews lists a product's approved reviews, newest first
+func (s *reviewService) GetProductReviews(productID int, limit int, offset int) (*ReviewPage, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, product.ErrProductNotFound
+	}
+	return s.GetReviews(ReviewFilter{
+		ProductID: productID,
+		Status:    StatusApproved,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+func (s *reviewService) GetReviews(filter ReviewFilter) (*ReviewPage, error) {
+	if filter.Status != "" && !IsValidStatus(filter.Status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, filter.Status)
+	}
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = DefaultPageLimit
+	case filter.Limit < 0 || filter.Limit > MaxPageLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, MaxPageLimit)
+	}
+	if filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidFilter)
+	}
+
+	reviews, total, err := s.repo.FindAll(filter)
+	if err != nil {
+		return nil, err
+	}
+	if reviews == nil {
+		reviews = []Review{}
+	}
+	return &ReviewPage{Data: reviews, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// ModerateReview approves, rejects or hides a review, then refreshes the
+// product's rating, which counts approved reviews only
+func (s *reviewService) ModerateReview(id int, status string, req ModerateReviewRequest) (*Review, error) {
+	review, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+	if !slices.Contains(transitions[review.Status], status) {
+		return nil, fmt.Errorf("%w: cannot go from %s to %s", ErrInvalidTransition, review.Status, status)
+	}
+
+	if err := s.repo.UpdateStatus(id, review.Status, status, req.Note); err != nil {
+		return nil, err
+	}
+	if err := s.refreshRating(review.ProductID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+// refreshRating recomputes a product's average rating and review count
+func (s *reviewService) refreshRating(productID int) error {
+	average, count, err := s.repo.RatingSummary(productID)
+	if err != nil {
+		return err
+	}
+	return s.productRepo.SetRating(productID, math.Round(average*100)/100, count)
+}
@@ -1,23 +1,55 @@
 package router
 
 import (
+	"log"
+	"strings"
+
 	"gorm.io/gorm"
 
+	"mini-ecommerce/config"
 	"mini-ecommerce/internal/admin"
+	"mini-ecommerce/internal/cart"
+	"mini-ecommerce/internal/category"
 	"mini-ecommerce/internal/order"
+	"mini-ecommerce/internal/payment"
 	"mini-ecommerce/internal/product"
+	"mini-ecommerce/internal/promotion"
+	"mini-ecommerce/internal/returns"
+	"mini-ecommerce/internal/review"
+	"mini-ecommerce/internal/sale"
+	"mini-ecommerce/internal/shipping"
+	"mini-ecommerce/internal/tax"
 	"mini-ecommerce/internal/user"
 	"mini-ecommerce/pkg/middleware"
+	"mini-ecommerce/pkg/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+func SetupRouter(db *gorm.DB, cfg config.Config) *gin.Engine {
 	r := gin.Default()
 
-	// Initialize product repository, service, and handler
+	// Initialize category repository, service, and handler
+	categoryRepo := category.NewCategoryRepository(db)
+	categoryService := category.NewCategoryService(categoryRepo)
+	categoryHandler := category.NewCategoryHandler(categoryService)
+
+	// Initialize upload storage
+	store, err := storage.New(cfg.StorageDriver, cfg.StorageDir, cfg.StorageBaseURL)
+	if err != nil {
+		log.Fatalf("Failed to set up storage: %v", err)
+	}
+	// Serve local uploads when they are addressed by a path on this server
+	if _, ok := store.(*storage.LocalStorage); ok && strings.HasPrefix(cfg.StorageBaseURL, "/") {
+		r.Static(cfg.StorageBaseURL, cfg.StorageDir)
+	}
+
+	// Initialize product and sale repositories, services, and handlers
 	productRepo := product.NewProductRepository(db)
-	productService := product.NewProductService(productRepo)
+	saleRepo := sale.NewSaleRepository(db)
+	saleService := sale.NewSaleService(saleRepo, productRepo, categoryRepo)
+	saleHandler := sale.NewSaleHandler(saleService)
+	productService := product.NewProductService(productRepo, categoryRepo, store, saleService)
 	productHandler := product.NewProductHandler(productService)
 
 	// Initialize admin repository, service, and handler
@@ -30,25 +62,116 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 	userService := user.NewUserService(userRepo)
 	userHandler := user.NewUserHandler(userService)
 
+	// Initialize promotion repository, service, and handler
+	couponRepo := promotion.NewCouponRepository(db)
+	promotionService := promotion.NewPromotionService(couponRepo)
+	promotionHandler := promotion.NewPromotionHandler(promotionService)
+
+	// Initialize tax repository, service, and handler
+	taxRepo := tax.NewTaxRuleRepository(db)
+	taxService := tax.NewTaxService(taxRepo)
+	taxHandler := tax.NewTaxHandler(taxService)
+
+	// Initialize shipping repository, service, and handler
+	shippingRepo := shipping.NewShippingRepository(db)
+	shippingService := shipping.NewShippingService(shippingRepo, saleService)
+	shippingHandler := shipping.NewShippingHandler(shippingService, productRepo)
+
 	// Initialize order repository, service, and handler
 	orderRepo := order.NewOrderRepository(db)
-	orderService := order.NewOrderService(orderRepo)
+	orderService := order.NewOrderService(orderRepo, promotionService, taxService, shippingService, saleService)
 	orderHandler := order.NewOrderHandler(orderService, productRepo)
 
+	// Initialize cart repository, service, and handler
+	cartRepo := cart.NewCartRepository(db)
+	cartService := cart.NewCartService(cartRepo, productRepo, orderService, saleService)
+	cartHandler := cart.NewCartHandler(cartService)
+
+	// Initialize review repository, service, and handler
+	reviewRepo := review.NewReviewRepository(db)
+	reviewService := review.NewReviewService(reviewRepo, productRepo)
+	reviewHandler := review.NewReviewHandler(reviewService)
+
+	// Initialize payment gateway, repository, service, and handler
+	if err := cfg.CheckPayments(); err != nil {
+		log.Fatalf("Invalid payment settings: %v", err)
+	}
+	gateway, err := payment.NewGateway(cfg.PaymentProvider, cfg.PaymentWebhookSecret, cfg.PaymentFakeEnabled)
+	if err != nil {
+		log.Fatalf("Failed to set up payment gateway: %v", err)
+	}
+	paymentRepo := payment.NewPaymentRepository(db)
+	paymentService := payment.NewPaymentService(paymentRepo, gateway, orderService)
+	orderService.SetRefunder(paymentService)
+	paymentHandler := payment.NewPaymentHandler(paymentService, orderService)
+
+	// Initialize return repository, service, and handler
+	returnRepo := returns.NewReturnRepository(db)
+	returnService := returns.NewReturnService(returnRepo, orderService, paymentService)
+	returnHandler := returns.NewReturnHandler(returnService, orderService)
+
 	// Product routes
 	productRoutes := r.Group("/api/v1/products")
 	{
 		// Customer routes (public)
 		productRoutes.GET("", productHandler.GetAllProducts)
+		productRoutes.GET("/search", productHandler.SearchProducts)
+		productRoutes.GET("/suggest", productHandler.SuggestProducts)
 		productRoutes.GET("/:id", productHandler.GetProductByID)
+		productRoutes.GET("/:id/reviews", reviewHandler.GetProductReviews)
+
+		// Customer routes (logged-in users)
+		userProduct := productRoutes.Group("")
+		userProduct.Use(middleware.AuthMiddleware(), middleware.UserMiddleware())
+		{
+			userProduct.POST("/:id/reviews", reviewHandler.CreateReview)
+		}
 
 		// Admin routes (protected)
 		adminProduct := productRoutes.Group("")
 		adminProduct.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
 		{
+			adminProduct.GET("/manage", productHandler.GetManagedProducts)
+			adminProduct.GET("/manage/:id", productHandler.GetManagedProduct)
+			adminProduct.GET("/trash", productHandler.GetDeletedProducts)
 			adminProduct.POST("", productHandler.CreateProduct)
+			adminProduct.POST("/import", productHandler.ImportProducts)
+			adminProduct.GET("/export", productHandler.ExportProducts)
 			adminProduct.PUT("/:id", productHandler.UpdateProduct)
+			adminProduct.PATCH("/:id", productHandler.PatchProduct)
+			adminProduct.PUT("/:id/stock", productHandler.UpdateStock)
+			adminProduct.PUT("/:id/status", productHandler.SetStatus)
+			adminProduct.PUT("/:id/categories", productHandler.SetCategories)
+			adminProduct.GET("/:id/price-history", productHandler.GetPriceHistory)
+			adminProduct.POST("/:id/variants", productHandler.CreateVariant)
+			adminProduct.POST("/:id/variants/generate", productHandler.GenerateVariants)
+			adminProduct.PUT("/:id/variants/:variant_id", productHandler.UpdateVariant)
+			adminProduct.DELETE("/:id/variants/:variant_id", productHandler.DeleteVariant)
+			adminProduct.POST("/:id/images", productHandler.UploadImages)
+			adminProduct.PUT("/:id/images/order", productHandler.ReorderImages)
+			adminProduct.DELETE("/:id/images/:image_id", productHandler.DeleteImage)
 			adminProduct.DELETE("/:id", productHandler.DeleteProduct)
+			adminProduct.PUT("/:id/restore", productHandler.RestoreProduct)
+			adminProduct.DELETE("/:id/purge", productHandler.PurgeProduct)
+		}
+	}
+
+	// Category routes
+	categoryRoutes := r.Group("/api/v1/categories")
+	{
+		// Customer routes (public); :id also accepts a slug
+		categoryRoutes.GET("", categoryHandler.GetCategoryTree)
+		categoryRoutes.GET("/:id", categoryHandler.GetCategory)
+		categoryRoutes.GET("/:id/products", productHandler.GetCategoryProducts)
+
+		// Admin routes (protected)
+		adminCategory := categoryRoutes.Group("")
+		adminCategory.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		{
+			adminCategory.POST("", categoryHandler.CreateCategory)
+			adminCategory.PUT("/:id", categoryHandler.UpdateCategory)
+			adminCategory.PUT("/:id/move", categoryHandler.MoveCategory)
+			adminCategory.DELETE("/:id", categoryHandler.DeleteCategory)
 		}
 	}
 
@@ -65,6 +188,7 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 			protectedAdmin.GET("", adminHandler.GetAllAdmins)
 			protectedAdmin.GET("/:id", adminHandler.GetAdminByID)
 			protectedAdmin.PUT("/:id", adminHandler.UpdateAdmin)
+			protectedAdmin.PATCH("/:id", adminHandler.PatchAdmin)
 			protectedAdmin.DELETE("/:id", adminHandler.DeleteAdmin)
 		}
 	}
@@ -75,12 +199,26 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		userRoutes.POST("/register", userHandler.Register)
 		userRoutes.POST("/login", userHandler.Login)
 
-		// Protected user routes
+		// Self-service routes for the logged-in user
+		me := userRoutes.Group("/me")
+		me.Use(middleware.AuthMiddleware(), middleware.UserMiddleware())
+		{
+			me.GET("", userHandler.GetMe)
+			me.PUT("", userHandler.UpdateMe)
+			me.PATCH("", userHandler.PatchMe)
+			me.DELETE("", userHandler.DeleteMe)
+			me.PUT("/password", userHandler.ChangePassword)
+			me.GET("/orders", orderHandler.GetMyOrders)
+			me.GET("/reviews", reviewHandler.GetMyReviews)
+		}
+
+		// Profile routes (the user themselves or an admin)
 		protectedUser := userRoutes.Group("")
-		protectedUser.Use(middleware.AuthMiddleware(), middleware.UserMiddleware())
+		protectedUser.Use(middleware.AuthMiddleware())
 		{
 			protectedUser.GET("/profile/:id", userHandler.GetProfile)
 			protectedUser.PUT("/profile/:id", userHandler.UpdateProfile)
+			protectedUser.PATCH("/profile/:id", userHandler.PatchProfile)
 		}
 
 		// Admin only
@@ -102,18 +240,133 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 			protectedOrder.POST("", orderHandler.CreateOrder)
 			protectedOrder.GET("/user/:user_id", orderHandler.GetUserOrders)
 			protectedOrder.GET("/:id", orderHandler.GetOrderByID)
+			protectedOrder.GET("/:id/history", orderHandler.GetOrderHistory)
 			protectedOrder.DELETE("/:id", orderHandler.CancelOrder)
 
 			// Admin only
-			adminOrder := orderRoutes.Group("")
+			adminOrder := protectedOrder.Group("")
 			adminOrder.Use(middleware.AdminMiddleware())
 			{
 				adminOrder.GET("", orderHandler.GetAllOrders)
 				adminOrder.PUT("/:id/status", orderHandler.UpdateOrderStatus)
+				adminOrder.DELETE("/:id/purge", orderHandler.PurgeOrder)
 			}
 		}
 	}
 
+	// Cart routes (authenticated user's own cart)
+	cartRoutes := r.Group("/api/v1/cart")
+	cartRoutes.Use(middleware.AuthMiddleware(), middleware.UserMiddleware())
+	{
+		cartRoutes.GET("", cartHandler.GetCart)
+		cartRoutes.DELETE("", cartHandler.ClearCart)
+		cartRoutes.POST("/items", cartHandler.AddItem)
+		cartRoutes.PUT("/items/:product_id", cartHandler.UpdateItem)
+		cartRoutes.DELETE("/items/:product_id", cartHandler.RemoveItem)
+		cartRoutes.POST("/checkout", cartHandler.Checkout)
+	}
+
+	// Payment routes
+	paymentRoutes := r.Group("/api/v1/payments")
+	{
+		// Provider webhooks are authenticated by signature, not by token
+		paymentRoutes.POST("/webhook", paymentHandler.Webhook)
+
+		protectedPayment := paymentRoutes.Group("")
+		protectedPayment.Use(middleware.AuthMiddleware())
+		{
+			protectedPayment.POST("", paymentHandler.StartPayment)
+			protectedPayment.GET("/order/:order_id", paymentHandler.GetOrderPayments)
+
+			// Lets clients complete a payment without an external provider. Only
+			// registered when the fake provider is enabled for development.
+			if fake, ok := gateway.(*payment.FakeGateway); ok && cfg.PaymentFakeEnabled {
+				protectedPayment.POST("/fake/:intent_id/authorize", paymentHandler.FakeAuthorize(fake))
+			}
+		}
+	}
+
+	// Return (RMA) routes
+	returnRoutes := r.Group("/api/v1/returns")
+	returnRoutes.Use(middleware.AuthMiddleware())
+	{
+		returnRoutes.POST("", returnHandler.CreateReturn)
+		returnRoutes.GET("/mine", returnHandler.GetMyReturns)
+		returnRoutes.GET("/:id", returnHandler.GetReturnByID)
+
+		// Admin only
+		adminReturn := returnRoutes.Group("")
+		adminReturn.Use(middleware.AdminMiddleware())
+		{
+			adminReturn.GET("", returnHandler.GetReturns)
+			adminReturn.PUT("/:id/approve", returnHandler.ApproveReturn)
+			adminReturn.PUT("/:id/reject", returnHandler.RejectReturn)
+			adminReturn.PUT("/:id/receive", returnHandler.ReceiveReturn)
+		}
+	}
+
+	// Review moderation routes (admin only)
+	reviewRoutes := r.Group("/api/v1/reviews")
+	reviewRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		reviewRoutes.GET("", reviewHandler.GetReviews)
+		reviewRoutes.PUT("/:id/approve", reviewHandler.ApproveReview)
+		reviewRoutes.PUT("/:id/reject", reviewHandler.RejectReview)
+		reviewRoutes.PUT("/:id/hide", reviewHandler.HideReview)
+	}
+
+	// Coupon routes (admin only)
+	couponRoutes := r.Group("/api/v1/coupons")
+	couponRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		couponRoutes.POST("", promotionHandler.CreateCoupon)
+		couponRoutes.GET("", promotionHandler.GetAllCoupons)
+		couponRoutes.GET("/:id", promotionHandler.GetCouponByID)
+		couponRoutes.PUT("/:id", promotionHandler.UpdateCoupon)
+		couponRoutes.DELETE("/:id", promotionHandler.DeleteCoupon)
+	}
+
+	// Sale routes (admin only)
+	saleRoutes := r.Group("/api/v1/sales")
+	saleRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		saleRoutes.POST("", saleHandler.ScheduleSale)
+		saleRoutes.POST("/preview", saleHandler.PreviewSale)
+		saleRoutes.GET("", saleHandler.GetSales)
+		saleRoutes.GET("/:id", saleHandler.GetSale)
+		saleRoutes.PUT("/:id/cancel", saleHandler.CancelSale)
+	}
+
+	// Tax rule routes (admin only)
+	taxRoutes := r.Group("/api/v1/tax-rules")
+	taxRoutes.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		taxRoutes.POST("", taxHandler.CreateTaxRule)
+		taxRoutes.GET("", taxHandler.GetAllTaxRules)
+		taxRoutes.GET("/:id", taxHandler.GetTaxRuleByID)
+		taxRoutes.PUT("/:id", taxHandler.UpdateTaxRule)
+		taxRoutes.DELETE("/:id", taxHandler.DeleteTaxRule)
+	}
+
+	// Shipping routes
+	shippingRoutes := r.Group("/api/v1/shipping")
+	{
+		shippingRoutes.POST("/quote", shippingHandler.Quote)
+
+		adminShipping := shippingRoutes.Group("")
+		adminShipping.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		{
+			adminShipping.POST("/zones", shippingHandler.CreateZone)
+			adminShipping.GET("/zones", shippingHandler.GetAllZones)
+			adminShipping.GET("/zones/:id", shippingHandler.GetZoneByID)
+			adminShipping.PUT("/zones/:id", shippingHandler.UpdateZone)
+			adminShipping.DELETE("/zones/:id", shippingHandler.DeleteZone)
+			adminShipping.POST("/zones/:id/methods", shippingHandler.CreateMethod)
+			adminShipping.PUT("/methods/:id", shippingHandler.UpdateMethod)
+			adminShipping.DELETE("/methods/:id", shippingHandler.DeleteMethod)
+		}
+	}
+
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "Server is running"})
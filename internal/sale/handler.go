@@ -0,0 +1,108 @@
+package sale
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SaleHandler struct {
+	service SaleService
+}
+
+func NewSaleHandler(service SaleService) *SaleHandler {
+	return &SaleHandler{service: service}
+}
+
+// ScheduleSale creates a sale that runs between its start and end times
+// (admin only)
+func (h *SaleHandler) ScheduleSale(c *gin.Context) {
+	var req SaleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	sale, err := h.service.ScheduleSale(req, c.GetInt("userID"))
+	if err != nil {
+		respondError(c, err, "Failed to schedule sale")
+		return
+	}
+	c.JSON(http.StatusCreated, sale)
+}
+
+// PreviewSale shows the products a sale would cover with their regular and
+// sale prices, without scheduling it (admin only)
+func (h *SaleHandler) PreviewSale(c *gin.Context) {
+	var req SaleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	preview, err := h.service.PreviewSale(req)
+	if err != nil {
+		respondError(c, err, "Failed to preview sale")
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// GetSales lists sales, optionally only those with the given ?status=
+// (admin only)
+func (h *SaleHandler) GetSales(c *gin.Context) {
+	sales, err := h.service.GetSales(c.Query("status"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch sales")
+		return
+	}
+	c.JSON(http.StatusOK, sales)
+}
+
+// GetSale retrieves a sale (admin only)
+func (h *SaleHandler) GetSale(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sale ID"})
+		return
+	}
+
+	sale, err := h.service.GetSale(id)
+	if err != nil {
+		respondError(c, err, "Failed to fetch sale")
+		return
+	}
+	c.JSON(http.StatusOK, sale)
+}
+
+// CancelSale calls off a scheduled sale, or ends a running one (admin only)
+func (h *SaleHandler) CancelSale(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sale ID"})
+		return
+	}
+
+	sale, err := h.service.CancelSale(id)
+	if err != nil {
+		respondError(c, err, "Failed to cancel sale")
+		return
+	}
+	c.JSON(http.StatusOK, sale)
+}
+
+// respondError maps sale errors to status codes
+func respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrSaleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sale not found"})
+	case errors.Is(err, ErrInvalidSale), errors.Is(err, ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
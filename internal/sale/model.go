@@ -0,0 +1,143 @@
+package sale
+
+import (
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Sale types
+const (
+	TypePercentage = "percentage" // Value is a percentage off the regular price
+	TypeFixed      = "fixed"      // Amount is taken off the regular price
+	TypePrice      = "price"      // Amount is the sale price
+)
+
+// Sale statuses. They follow from the schedule and are not stored.
+const (
+	StatusScheduled = "scheduled"
+	StatusActive    = "active"
+	StatusEnded     = "ended"
+	StatusCancelled = "cancelled"
+)
+
+// MaxPreviewProducts caps the products listed by a sale preview
+const MaxPreviewProducts = 100
+
+// Sale lowers the price of the chosen products, and of every product in the
+// chosen categories or their subcategories, between StartsAt and EndsAt.
+// When several sales cover a product the lowest price wins.
+type Sale struct {
+	ID          int            `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"` // shown to customers with the sale price
+	Type        string         `json:"type" gorm:"not null"`
+	Value       float64        `json:"value,omitempty"`                               // percent, for percentage sales
+	Amount      money.Money    `json:"amount" gorm:"embedded;embeddedPrefix:amount_"` // for fixed and price sales
+	StartsAt    time.Time      `json:"starts_at" gorm:"not null;index"`
+	EndsAt      time.Time      `json:"ends_at" gorm:"not null;index"`
+	CancelledAt *time.Time     `json:"cancelled_at"`
+	CreatedBy   int            `json:"created_by"` // admin ID
+	Products    []SaleProduct  `json:"products" gorm:"foreignKey:SaleID;constraint:OnDelete:CASCADE"`
+	Categories  []SaleCategory `json:"categories" gorm:"foreignKey:SaleID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	// Status is worked out from the schedule when the sale is returned
+	Status string `json:"status" gorm:"-"`
+}
+
+// SaleProduct puts a product in a sale
+type SaleProduct struct {
+	ID        int `json:"-" gorm:"primaryKey"`
+	SaleID    int `json:"-" gorm:"not null;index"`
+	ProductID int `json:"product_id" gorm:"not null;index"`
+}
+
+// SaleCategory puts a category, and everything below it, in a sale
+type SaleCategory struct {
+	ID         int `json:"-" gorm:"primaryKey"`
+	SaleID     int `json:"-" gorm:"not null;index"`
+	CategoryID int `json:"category_id" gorm:"not null;index"`
+}
+
+// SaleRequest schedules a sale. It must name at least one product or category.
+type SaleRequest struct {
+	Name        string      `json:"name" binding:"required,max=200"`
+	Type        string      `json:"type" binding:"required,oneof=percentage fixed price"`
+	Value       float64     `json:"value" binding:"gte=0,lte=100"` // percentage sales
+	Amount      money.Money `json:"amount"`                        // fixed and price sales
+	StartsAt    *time.Time  `json:"starts_at"`                     // defaults to now
+	EndsAt      time.Time   `json:"ends_at" binding:"required"`
+	ProductIDs  []int       `json:"product_ids" binding:"omitempty,dive,gt=0"`
+	CategoryIDs []int       `json:"category_ids" binding:"omitempty,dive,gt=0"`
+}
+
+// Coverage is a product covered by a sale
+type Coverage struct {
+	SaleID    int
+	ProductID int
+}
+
+// PreviewItem is a product or variant's regular and sale price
+type PreviewItem struct {
+	ProductID    int         `json:"product_id"`
+	VariantID    int         `json:"variant_id,omitempty"`
+	Name         string      `json:"name"`
+	SKU          string      `json:"sku,omitempty"`
+	RegularPrice money.Money `json:"regular_price"`
+	SalePrice    money.Money `json:"sale_price"`
+}
+
+// Preview lists what a sale would cover and at what price. Items holds the
+// first MaxPreviewProducts products, with their variants.
+type Preview struct {
+	Sale    *Sale         `json:"sale"`
+	Covered int           `json:"covered"` // products covered in all
+	Items   []PreviewItem `json:"items"`
+}
+
+// StatusAt works out the sale's status at the given time
+func (s *Sale) StatusAt(at time.Time) string {
+	switch {
+	case s.CancelledAt != nil:
+		return StatusCancelled
+	case at.Before(s.StartsAt):
+		return StatusScheduled
+	case at.Before(s.EndsAt):
+		return StatusActive
+	default:
+		return StatusEnded
+	}
+}
+
+// PriceFor returns the sale price of something regularly sold at price, and
+// whether the sale applies to it. It does not when it would leave the price
+// unchanged or bring it down to zero, so nothing is given away, nor to
+// prices in another currency than its amount.
+func (s *Sale) PriceFor(price money.Money) (money.Money, bool) {
+	if s.Type != TypePercentage && !s.Amount.SameCurrency(price) {
+		return price, false
+	}
+	var salePrice money.Money
+	switch s.Type {
+	case TypePercentage:
+		salePrice = price.Sub(price.Percent(s.Value))
+	case TypeFixed:
+		salePrice = price.Sub(money.Min(s.Amount, price))
+	default:
+		salePrice = money.Min(s.Amount, price)
+	}
+	if !salePrice.IsPositive() || salePrice.Cmp(price) >= 0 {
+		return price, false
+	}
+	return salePrice, true
+}
+
+// IsValidStatus reports whether status is a known sale status
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusScheduled, StatusActive, StatusEnded, StatusCancelled:
+		return true
+	}
+	return false
+}
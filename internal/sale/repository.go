@@ -0,0 +1,119 @@
+package sale
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SaleRepository interface {
+	Create(sale *Sale) error
+	FindByID(id int) (*Sale, error)
+	FindByIDs(ids []int) ([]Sale, error)
+	FindAll(status string, at time.Time) ([]Sale, error)
+	FindCoverage(productIDs []int, at time.Time) ([]Coverage, error)
+	CoveredProductIDs(productIDs []int, categoryIDs []int) ([]int, error)
+	Cancel(id int, at time.Time) error
+}
+
+// activeSQL selects sales running at the time bound to both placeholders
+const activeSQL = "cancelled_at IS NULL AND starts_at <= ? AND ends_at > ?"
+
+type saleRepository struct {
+	db *gorm.DB
+}
+
+func NewSaleRepository(db *gorm.DB) SaleRepository {
+	return &saleRepository{db: db}
+}
+
+func (r *saleRepository) Create(sale *Sale) error {
+	return r.db.Create(sale).Error
+}
+
+func (r *saleRepository) FindByID(id int) (*Sale, error) {
+	var sale Sale
+	err := r.db.Preload("Products").Preload("Categories").First(&sale, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+func (r *saleRepository) FindByIDs(ids []int) ([]Sale, error) {
+	var sales []Sale
+	err := r.db.Where("id IN ?", ids).Find(&sales).Error
+	return sales, err
+}
+
+// FindAll returns the sales with the given status at a time, or all sales
+// for an empty status, soonest to start first
+func (r *saleRepository) FindAll(status string, at time.Time) ([]Sale, error) {
+	query := r.db.Preload("Products").Preload("Categories")
+	switch status {
+	case StatusScheduled:
+		query = query.Where("cancelled_at IS NULL AND starts_at > ?", at)
+	case StatusActive:
+		query = query.Where(activeSQL, at, at)
+	case StatusEnded:
+		query = query.Where("cancelled_at IS NULL AND ends_at <= ?", at)
+	case StatusCancelled:
+		query = query.Where("cancelled_at IS NOT NULL")
+	}
+
+	var sales []Sale
+	err := query.Order("starts_at, id").Find(&sales).Error
+	return sales, err
+}
+
+// FindCoverage finds which of the products are in a sale running at the
+// given time, directly or through one of their categories
+func (r *saleRepository) FindCoverage(productIDs []int, at time.Time) ([]Coverage, error) {
+	var coverage []Coverage
+	err := r.db.Raw(`WITH RECURSIVE active AS (
+			SELECT id FROM sales WHERE `+activeSQL+`
+		), covered AS (
+			SELECT sc.sale_id, sc.category_id FROM sale_categories sc JOIN active ON active.id = sc.sale_id
+			UNION
+			SELECT covered.sale_id, c.id FROM categories c JOIN covered ON c.parent_id = covered.category_id
+		)
+		SELECT covered.sale_id, pc.product_id FROM covered
+		JOIN product_categories pc ON pc.category_id = covered.category_id
+		WHERE pc.product_id IN ?
+		UNION
+		SELECT sp.sale_id, sp.product_id FROM sale_products sp JOIN active ON active.id = sp.sale_id
+		WHERE sp.product_id IN ?`,
+		at, at, productIDs, productIDs).Scan(&coverage).Error
+	return coverage, err
+}
+
+// CoveredProductIDs returns the IDs of the products a sale of the given
+// products and categories would cover, in order
+func (r *saleRepository) CoveredProductIDs(productIDs []int, categoryIDs []int) ([]int, error) {
+	var ids []int
+	err := r.db.Raw(`WITH RECURSIVE covered AS (
+			SELECT id FROM categories WHERE id IN ?
+			UNION
+			SELECT c.id FROM categories c JOIN covered ON c.parent_id = covered.id
+		)
+		SELECT id FROM products
+		WHERE deleted_at IS NULL
+		AND (id IN ? OR id IN (SELECT product_id FROM product_categories WHERE category_id IN (SELECT id FROM covered)))
+		ORDER BY id`,
+		categoryIDs, productIDs).Scan(&ids).Error
+	return ids, err
+}
+
+// Cancel ends a sale that has not ended yet, or stops it from starting
+func (r *saleRepository) Cancel(id int, at time.Time) error {
+	result := r.db.Model(&Sale{}).Where("id = ? AND cancelled_at IS NULL AND ends_at > ?", id, at).
+		Update("cancelled_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: sale has already ended or been cancelled", ErrInvalidTransition)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package sale
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"mini-ecommerce/internal/category"
+	"mini-ecommerce/internal/product"
+	"mini-ecommerce/pkg/money"
+)
+
+var (
+	ErrSaleNotFound = errors.New("sale not found")
+	// ErrInvalidSale wraps every reason a sale cannot be scheduled
+	ErrInvalidSale       = errors.New("invalid sale")
+	ErrInvalidTransition = errors.New("invalid sale status change")
+	ErrInvalidFilter     = errors.New("invalid filter")
+)
+
+type SaleService interface {
+	ScheduleSale(req SaleRequest, adminID int) (*Sale, error)
+	PreviewSale(req SaleRequest) (*Preview, error)
+	GetSales(status string) ([]Sale, error)
+	GetSale(id int) (*Sale, error)
+	CancelSale(id int) (*Sale, error)
+	ApplySales(products []*product.Product) error
+}
+
+type saleService struct {
+	repo         SaleRepository
+	productRepo  product.ProductRepository
+	categoryRepo category.CategoryRepository
+}
+
+func NewSaleService(repo SaleRepository, productRepo product.ProductRepository, categoryRepo category.CategoryRepository) SaleService {
+	return &saleService{repo: repo, productRepo: productRepo, categoryRepo: categoryRepo}
+}
+
+// ScheduleSale saves a sale, which takes effect at its start time
+func (s *saleService) ScheduleSale(req SaleRequest, adminID int) (*Sale, error) {
+	sale, err := s.saleFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	sale.CreatedBy = adminID
+	if err := s.repo.Create(sale); err != nil {
+		return nil, err
+	}
+	sale.Status = sale.StatusAt(time.Now())
+	return sale, nil
+}
+
+// PreviewSale works out what a sale would cover and the prices it would
+// charge, without saving it. Other sales are not taken into account.
+func (s *saleService) PreviewSale(req SaleRequest) (*Preview, error) {
+	sale, err := s.saleFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	sale.Status = sale.StatusAt(time.Now())
+
+	ids, err := s.repo.CoveredProductIDs(productIDs(sale), categoryIDs(sale))
+	if err != nil {
+		return nil, err
+	}
+	preview := &Preview{Sale: sale, Covered: len(ids), Items: []PreviewItem{}}
+	for _, id := range ids[:min(len(ids), MaxPreviewProducts)] {
+		p, err := s.productRepo.FindByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Variants) == 0 {
+			preview.Items = append(preview.Items, PreviewItem{
+				ProductID:    p.ID,
+				Name:         p.Name,
+				SKU:          p.SKU,
+				RegularPrice: p.Price,
+				SalePrice:    salePrice(sale, p.Price),
+			})
+		}
+		for _, v := range p.Variants {
+			preview.Items = append(preview.Items, PreviewItem{
+				ProductID:    p.ID,
+				VariantID:    v.ID,
+				Name:         fmt.Sprintf("%s (%s)", p.Name, v.Label()),
+				SKU:          v.SKU,
+				RegularPrice: v.Price,
+				SalePrice:    salePrice(sale, v.Price),
+			})
+		}
+	}
+	return preview, nil
+}
+
+// GetSales lists sales with the given status now, or every sale for an
+// empty status
+func (s *saleService) GetSales(status string) ([]Sale, error) {
+	if status != "" && !IsValidStatus(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, status)
+	}
+	now := time.Now()
+	sales, err := s.repo.FindAll(status, now)
+	if err != nil {
+		return nil, err
+	}
+	if sales == nil {
+		sales = []Sale{}
+	}
+	for i := range sales {
+		sales[i].Status = sales[i].StatusAt(now)
+	}
+	return sales, nil
+}
+
+func (s *saleService) GetSale(id int) (*Sale, error) {
+	sale, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrSaleNotFound
+	}
+	sale.Status = sale.StatusAt(time.Now())
+	return sale, nil
+}
+
+// CancelSale stops a scheduled sale from starting, or ends a running one now
+func (s *saleService) CancelSale(id int) (*Sale, error) {
+	if _, err := s.GetSale(id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Cancel(id, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.GetSale(id)
+}
+
+// ApplySales fills in the sale price of the products, and of their variants,
+// in sales running now. Where several sales cover a product the lowest price
+// wins; a sale that would not lower the price is ignored.
+func (s *saleService) ApplySales(products []*product.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	coverage, err := s.repo.FindCoverage(ids, time.Now())
+	if err != nil || len(coverage) == 0 {
+		return err
+	}
+
+	saleIDs := make([]int, 0, len(coverage))
+	for _, c := range coverage {
+		saleIDs = append(saleIDs, c.SaleID)
+	}
+	sales, err := s.repo.FindByIDs(slices.Compact(slices.Sorted(slices.Values(saleIDs))))
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]*Sale, len(sales))
+	for i := range sales {
+		byID[sales[i].ID] = &sales[i]
+	}
+	covering := make(map[int][]*Sale)
+	for _, c := range coverage {
+		if sale, ok := byID[c.SaleID]; ok {
+			covering[c.ProductID] = append(covering[c.ProductID], sale)
+		}
+	}
+
+	for _, p := range products {
+		for _, sale := range covering[p.ID] {
+			p.Sale = lowerPrice(p.Sale, sale, p.Price)
+			for i := range p.Variants {
+				v := &p.Variants[i]
+				v.Sale = lowerPrice(v.Sale, sale, v.Price)
+			}
+		}
+	}
+	return nil
+}
+
+// lowerPrice returns whichever is lower of current and what sale charges
+// for something regularly sold at price
+func lowerPrice(current *product.SalePrice, sale *Sale, price money.Money) *product.SalePrice {
+	salePrice, ok := sale.PriceFor(price)
+	if !ok || (current != nil && current.Price.Cmp(salePrice) <= 0) {
+		return current
+	}
+	return &product.SalePrice{SaleID: sale.ID, Name: sale.Name, Price: salePrice, EndsAt: sale.EndsAt}
+}
+
+// salePrice is what sale charges for something regularly sold at price, or
+// price itself where the sale does not apply
+func salePrice(sale *Sale, price money.Money) money.Money {
+	p, _ := sale.PriceFor(price)
+	return p
+}
+
+// saleFromRequest validates a request and builds the sale it describes
+func (s *saleService) saleFromRequest(req SaleRequest) (*Sale, error) {
+	now := time.Now()
+	sale := &Sale{
+		Name:       strings.TrimSpace(req.Name),
+		Type:       req.Type,
+		StartsAt:   now,
+		EndsAt:     req.EndsAt,
+		Products:   []SaleProduct{},
+		Categories: []SaleCategory{},
+	}
+	if sale.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidSale)
+	}
+
+	switch req.Type {
+	case TypePercentage:
+		if req.Value <= 0 || req.Value >= 100 {
+			return nil, fmt.Errorf("%w: percentage sales need a value above 0 and below 100", ErrInvalidSale)
+		}
+		sale.Value = req.Value
+		sale.Amount = money.Zero(money.DefaultCurrency())
+	case TypeFixed, TypePrice:
+		amount := req.Amount.OrDefault()
+		if !amount.IsPositive() || amount.Currency != money.DefaultCurrency() {
+			return nil, fmt.Errorf("%w: %s sales need a positive amount in %s", ErrInvalidSale, req.Type, money.DefaultCurrency())
+		}
+		sale.Amount = amount
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidSale, req.Type)
+	}
+
+	if req.StartsAt != nil {
+		sale.StartsAt = *req.StartsAt
+	}
+	if !sale.EndsAt.After(sale.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidSale)
+	}
+	if !sale.EndsAt.After(now) {
+		return nil, fmt.Errorf("%w: ends_at must be in the future", ErrInvalidSale)
+	}
+
+	if len(req.ProductIDs) == 0 && len(req.CategoryIDs) == 0 {
+		return nil, fmt.Errorf("%w: choose at least one product or category", ErrInvalidSale)
+	}
+	for _, id := range slices.Compact(slices.Sorted(slices.Values(req.ProductIDs))) {
+		p, err := s.productRepo.FindByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: product %d does not exist", ErrInvalidSale, id)
+		}
+		if err := checkNotFree(sale, p); err != nil {
+			return nil, err
+		}
+		sale.Products = append(sale.Products, SaleProduct{ProductID: id})
+	}
+	categoryIDs := slices.Compact(slices.Sorted(slices.Values(req.CategoryIDs)))
+	if len(categoryIDs) > 0 {
+		categories, err := s.categoryRepo.FindByIDs(categoryIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(categories) != len(categoryIDs) {
+			return nil, fmt.Errorf("%w: unknown category", ErrInvalidSale)
+		}
+	}
+	for _, id := range categoryIDs {
+		sale.Categories = append(sale.Categories, SaleCategory{CategoryID: id})
+	}
+	return sale, nil
+}
+
+// checkNotFree rejects a sale that would take the whole price off p or one
+// of its variants. Products the sale covers through a category are not
+// checked here; the sale just does not apply to any it would make free.
+func checkNotFree(sale *Sale, p *product.Product) error {
+	if sale.Type != TypeFixed {
+		return nil
+	}
+	free := func(price money.Money) bool {
+		return sale.Amount.SameCurrency(price) && sale.Amount.Cmp(price) >= 0
+	}
+	if len(p.Variants) == 0 && free(p.Price) {
+		return fmt.Errorf("%w: %s would be free; the amount must be below its price of %s", ErrInvalidSale, p.Name, p.Price)
+	}
+	for _, v := range p.Variants {
+		if free(v.Price) {
+			return fmt.Errorf("%w: %s (%s) would be free; the amount must be below its price of %s", ErrInvalidSale, p.Name, v.Label(), v.Price)
+		}
+	}
+	return nil
+}
+
+func productIDs(sale *Sale) []int {
+	ids := make([]int, len(sale.Products))
+	for i, p := range sale.Products {
+		ids[i] = p.ProductID
+	}
+	return ids
+}
+
+func categoryIDs(sale *Sale) []int {
+	ids := make([]int, len(sale.Categories))
+	for i, c := range sale.Categories {
+		ids[i] = c.CategoryID
+	}
+	return ids
+}
@@ -0,0 +1,203 @@
+package shipping
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mini-ecommerce/internal/product"
+)
+
+type ShippingHandler struct {
+	service     ShippingService
+	productRepo product.ProductRepository
+}
+
+func NewShippingHandler(service ShippingService, productRepo product.ProductRepository) *ShippingHandler {
+	return &ShippingHandler{
+		service:     service,
+		productRepo: productRepo,
+	}
+}
+
+// Quote lists the shipping methods and prices for items sent to a region
+func (h *ShippingHandler) Quote(c *gin.Context) {
+	var req QuoteRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	quote, err := h.service.Quote(req, h.productRepo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// CreateZone creates a shipping zone (admin only)
+func (h *ShippingHandler) CreateZone(c *gin.Context) {
+	var req ZoneRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	zone, err := h.service.CreateZone(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+// GetAllZones retrieves all shipping zones with their methods (admin only)
+func (h *ShippingHandler) GetAllZones(c *gin.Context) {
+	zones, err := h.service.GetAllZones()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipping zones"})
+		return
+	}
+
+	if len(zones) == 0 {
+		c.JSON(http.StatusOK, []Zone{})
+		return
+	}
+
+	c.JSON(http.StatusOK, zones)
+}
+
+// GetZoneByID retrieves a shipping zone (admin only)
+func (h *ShippingHandler) GetZoneByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	zone, err := h.service.GetZoneByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// UpdateZone renames a zone and replaces its regions (admin only)
+func (h *ShippingHandler) UpdateZone(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	var req ZoneRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	zone, err := h.service.UpdateZone(id, req)
+	if errors.Is(err, ErrZoneNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// DeleteZone deletes a zone with its methods (admin only)
+func (h *ShippingHandler) DeleteZone(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	if err := h.service.DeleteZone(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shipping zone deleted successfully"})
+}
+
+// CreateMethod adds a shipping method to a zone (admin only)
+func (h *ShippingHandler) CreateMethod(c *gin.Context) {
+	zoneID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	var req MethodRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	method, err := h.service.CreateMethod(zoneID, req)
+	if errors.Is(err, ErrZoneNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, method)
+}
+
+// UpdateMethod replaces a method's fees and rate table (admin only)
+func (h *ShippingHandler) UpdateMethod(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid method ID"})
+		return
+	}
+
+	var req MethodRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	method, err := h.service.UpdateMethod(id, req)
+	if errors.Is(err, ErrMethodNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, method)
+}
+
+// DeleteMethod deletes a shipping method (admin only)
+func (h *ShippingHandler) DeleteMethod(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid method ID"})
+		return
+	}
+
+	if err := h.service.DeleteMethod(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shipping method deleted successfully"})
+}
@@ -0,0 +1,112 @@
+package shipping
+
+import (
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// Zone groups the regions that share a set of shipping methods. A zone with
+// no regions is the fallback for regions no other zone lists.
+type Zone struct {
+	ID        int          `json:"id" gorm:"primaryKey"`
+	Name      string       `json:"name"`
+	Regions   []ZoneRegion `json:"regions" gorm:"foreignKey:ZoneID"`
+	Methods   []Method     `json:"methods" gorm:"foreignKey:ZoneID"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+func (Zone) TableName() string {
+	return "shipping_zones"
+}
+
+type ZoneRegion struct {
+	ID     int    `json:"-" gorm:"primaryKey"`
+	ZoneID int    `json:"-" gorm:"index"`
+	Region string `json:"region" gorm:"uniqueIndex"` // a region belongs to one zone
+}
+
+func (ZoneRegion) TableName() string {
+	return "shipping_zone_regions"
+}
+
+// Method is a way of shipping to a zone, e.g. standard or express. Its cost
+// is the flat fee plus the price of the first weight bracket the parcel fits.
+type Method struct {
+	ID            int          `json:"id" gorm:"primaryKey"`
+	ZoneID        int          `json:"zone_id" gorm:"index"`
+	Name          string       `json:"name"`
+	FlatFee       money.Money  `json:"flat_fee" gorm:"embedded;embeddedPrefix:flat_fee_"`
+	FreeThreshold money.Money  `json:"free_threshold" gorm:"embedded;embeddedPrefix:free_threshold_"` // ships free from this subtotal, 0 = never
+	Active        bool         `json:"active"`
+	Rates         []WeightRate `json:"rates" gorm:"foreignKey:MethodID"` // empty charges only the flat fee
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+func (Method) TableName() string {
+	return "shipping_methods"
+}
+
+// WeightRate is a weight bracket of a method's rate table
+type WeightRate struct {
+	ID        int         `json:"-" gorm:"primaryKey"`
+	MethodID  int         `json:"-" gorm:"index"`
+	MaxWeight float64     `json:"max_weight"` // in kg, inclusive
+	Price     money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+}
+
+func (WeightRate) TableName() string {
+	return "shipping_rates"
+}
+
+type ZoneRequest struct {
+	Name    string   `json:"name" binding:"required"`
+	Regions []string `json:"regions"`
+}
+
+type MethodRequest struct {
+	Name          string        `json:"name" binding:"required"`
+	FlatFee       money.Money   `json:"flat_fee"`
+	FreeThreshold money.Money   `json:"free_threshold"`
+	Active        *bool         `json:"active"` // defaults to true
+	Rates         []RateRequest `json:"rates" binding:"omitempty,dive"`
+}
+
+type RateRequest struct {
+	MaxWeight float64     `json:"max_weight" binding:"required,gt=0"`
+	Price     money.Money `json:"price"`
+}
+
+type QuoteItemRequest struct {
+	ProductID int `json:"product_id" binding:"required,gt=0"`
+	VariantID int `json:"variant_id" binding:"omitempty,gt=0"`
+	Quantity  int `json:"quantity" binding:"required,gt=0"`
+}
+
+type QuoteRequest struct {
+	Region string             `json:"region" binding:"required"`
+	Items  []QuoteItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// Parcel is what is being shipped
+type Parcel struct {
+	Weight   float64     // in kg
+	Subtotal money.Money // goods value after discounts
+}
+
+// Option is a shipping method available for a parcel, with its price
+type Option struct {
+	MethodID int         `json:"method_id"`
+	Name     string      `json:"name"`
+	Zone     string      `json:"zone"`
+	Cost     money.Money `json:"cost"`
+}
+
+type QuoteResponse struct {
+	Region   string      `json:"region"`
+	Weight   float64     `json:"weight"`
+	Subtotal money.Money `json:"subtotal"`
+	Methods  []Option    `json:"methods"`
+}
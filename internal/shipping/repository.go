@@ -0,0 +1,171 @@
+package shipping
+
+import (
+	"gorm.io/gorm"
+)
+
+type ShippingRepository interface {
+	CreateZone(zone *Zone) error
+	FindAllZones() ([]Zone, error)
+	FindZoneByID(id int) (*Zone, error)
+	FindZoneByRegion(region string) (*Zone, error)
+	FindFallbackZone() (*Zone, error)
+	UpdateZone(id int, zone *Zone) error
+	DeleteZone(id int) error
+	CreateMethod(method *Method) error
+	FindMethodByID(id int) (*Method, error)
+	UpdateMethod(id int, method *Method) error
+	DeleteMethod(id int) error
+}
+
+type shippingRepository struct {
+	db *gorm.DB
+}
+
+func NewShippingRepository(db *gorm.DB) ShippingRepository {
+	return &shippingRepository{db: db}
+}
+
+// zones preloads a zone's regions and its methods with their rate tables
+func (r *shippingRepository) zones() *gorm.DB {
+	return r.db.Preload("Regions").
+		Preload("Methods", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Methods.Rates", func(db *gorm.DB) *gorm.DB { return db.Order("max_weight") })
+}
+
+func (r *shippingRepository) CreateZone(zone *Zone) error {
+	return r.db.Create(zone).Error
+}
+
+func (r *shippingRepository) FindAllZones() ([]Zone, error) {
+	var zones []Zone
+	err := r.zones().Order("id").Find(&zones).Error
+	return zones, err
+}
+
+func (r *shippingRepository) FindZoneByID(id int) (*Zone, error) {
+	var zone Zone
+	err := r.zones().First(&zone, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+func (r *shippingRepository) FindZoneByRegion(region string) (*Zone, error) {
+	var zone Zone
+	err := r.zones().
+		Where("id = (?)", r.db.Model(&ZoneRegion{}).Select("zone_id").Where("region = ?", region)).
+		First(&zone).Error
+	if err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// FindFallbackZone returns the oldest zone without regions
+func (r *shippingRepository) FindFallbackZone() (*Zone, error) {
+	var zone Zone
+	err := r.zones().
+		Where("NOT EXISTS (?)", r.db.Model(&ZoneRegion{}).Select("1").Where("zone_id = shipping_zones.id")).
+		Order("id").First(&zone).Error
+	if err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// UpdateZone renames the zone and replaces its regions
+func (r *shippingRepository) UpdateZone(id int, zone *Zone) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Zone{}).Where("id = ?", id).Update("name", zone.Name).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("zone_id = ?", id).Delete(&ZoneRegion{}).Error; err != nil {
+			return err
+		}
+		for i := range zone.Regions {
+			zone.Regions[i].ZoneID = id
+		}
+		if len(zone.Regions) == 0 {
+			return nil
+		}
+		return tx.Create(&zone.Regions).Error
+	})
+}
+
+// DeleteZone removes the zone with its regions, methods and rates
+func (r *shippingRepository) DeleteZone(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		methodIDs := tx.Model(&Method{}).Select("id").Where("zone_id = ?", id)
+		if err := tx.Where("method_id IN (?)", methodIDs).Delete(&WeightRate{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("zone_id = ?", id).Delete(&Method{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("zone_id = ?", id).Delete(&ZoneRegion{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Zone{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *shippingRepository) CreateMethod(method *Method) error {
+	return r.db.Create(method).Error
+}
+
+func (r *shippingRepository) FindMethodByID(id int) (*Method, error) {
+	var method Method
+	err := r.db.Preload("Rates", func(db *gorm.DB) *gorm.DB { return db.Order("max_weight") }).
+		First(&method, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+// UpdateMethod saves every method field, including zero values, and replaces its rate table
+func (r *shippingRepository) UpdateMethod(id int, method *Method) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Method{}).Where("id = ?", id).
+			Select("*").Omit("id", "zone_id", "created_at", "Rates").
+			Updates(method).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("method_id = ?", id).Delete(&WeightRate{}).Error; err != nil {
+			return err
+		}
+		for i := range method.Rates {
+			method.Rates[i].MethodID = id
+		}
+		if len(method.Rates) == 0 {
+			return nil
+		}
+		return tx.Create(&method.Rates).Error
+	})
+}
+
+func (r *shippingRepository) DeleteMethod(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("method_id = ?", id).Delete(&WeightRate{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Method{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
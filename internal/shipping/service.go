@@ -0,0 +1,289 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mini-ecommerce/internal/product"
+	"mini-ecommerce/pkg/money"
+)
+
+var (
+	ErrZoneNotFound   = errors.New("shipping zone not found")
+	ErrMethodNotFound = errors.New("shipping method not found")
+	// ErrMethodUnavailable is returned when a method cannot ship a parcel to a region
+	ErrMethodUnavailable = errors.New("shipping method not available")
+)
+
+type ShippingService interface {
+	CreateZone(req ZoneRequest) (*Zone, error)
+	GetAllZones() ([]Zone, error)
+	GetZoneByID(id int) (*Zone, error)
+	UpdateZone(id int, req ZoneRequest) (*Zone, error)
+	DeleteZone(id int) error
+	CreateMethod(zoneID int, req MethodRequest) (*Method, error)
+	UpdateMethod(id int, req MethodRequest) (*Method, error)
+	DeleteMethod(id int) error
+	Quote(req QuoteRequest, productRepo product.ProductRepository) (*QuoteResponse, error)
+	Options(region string, parcel Parcel) ([]Option, error)
+	Price(methodID int, region string, parcel Parcel) (*Option, error)
+}
+
+type shippingService struct {
+	repo  ShippingRepository
+	sales product.SalePricer
+}
+
+func NewShippingService(repo ShippingRepository, sales product.SalePricer) ShippingService {
+	return &shippingService{repo: repo, sales: sales}
+}
+
+func (s *shippingService) CreateZone(req ZoneRequest) (*Zone, error) {
+	zone := &Zone{Name: strings.TrimSpace(req.Name)}
+	regions, err := s.zoneRegions(0, req.Regions)
+	if err != nil {
+		return nil, err
+	}
+	zone.Regions = regions
+
+	err = s.repo.CreateZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func (s *shippingService) GetAllZones() ([]Zone, error) {
+	return s.repo.FindAllZones()
+}
+
+func (s *shippingService) GetZoneByID(id int) (*Zone, error) {
+	zone, err := s.repo.FindZoneByID(id)
+	if err != nil {
+		return nil, ErrZoneNotFound
+	}
+	return zone, nil
+}
+
+func (s *shippingService) UpdateZone(id int, req ZoneRequest) (*Zone, error) {
+	if _, err := s.GetZoneByID(id); err != nil {
+		return nil, err
+	}
+
+	regions, err := s.zoneRegions(id, req.Regions)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.UpdateZone(id, &Zone{Name: strings.TrimSpace(req.Name), Regions: regions})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindZoneByID(id)
+}
+
+func (s *shippingService) DeleteZone(id int) error {
+	if err := s.repo.DeleteZone(id); err != nil {
+		return ErrZoneNotFound
+	}
+	return nil
+}
+
+func (s *shippingService) CreateMethod(zoneID int, req MethodRequest) (*Method, error) {
+	if _, err := s.GetZoneByID(zoneID); err != nil {
+		return nil, err
+	}
+
+	method, err := methodFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	method.ZoneID = zoneID
+	err = s.repo.CreateMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+func (s *shippingService) UpdateMethod(id int, req MethodRequest) (*Method, error) {
+	if _, err := s.repo.FindMethodByID(id); err != nil {
+		return nil, ErrMethodNotFound
+	}
+
+	method, err := methodFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.UpdateMethod(id, method)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindMethodByID(id)
+}
+
+func (s *shippingService) DeleteMethod(id int) error {
+	if err := s.repo.DeleteMethod(id); err != nil {
+		return ErrMethodNotFound
+	}
+	return nil
+}
+
+// Quote weighs and prices the given items and lists the methods that can
+// ship them to the region. Items are priced as an order would charge them,
+// at the sale price for products on sale.
+func (s *shippingService) Quote(req QuoteRequest, productRepo product.ProductRepository) (*QuoteResponse, error) {
+	parcel := Parcel{Subtotal: money.Zero(money.DefaultCurrency())}
+	for _, item := range req.Items {
+		prod, err := productRepo.FindByID(item.ProductID)
+		if err != nil || !prod.IsPublished(time.Now()) {
+			return nil, fmt.Errorf("product %d not found", item.ProductID)
+		}
+		if err := s.sales.ApplySales([]*product.Product{prod}); err != nil {
+			return nil, err
+		}
+		sel, err := prod.Select(item.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		parcel.Weight += sel.Weight * float64(item.Quantity)
+		parcel.Subtotal = parcel.Subtotal.Add(sel.Price.Mul(item.Quantity))
+	}
+
+	options, err := s.Options(req.Region, parcel)
+	if err != nil {
+		return nil, err
+	}
+	return &QuoteResponse{
+		Region:   NormalizeRegion(req.Region),
+		Weight:   parcel.Weight,
+		Subtotal: parcel.Subtotal,
+		Methods:  options,
+	}, nil
+}
+
+// Options lists the active methods of the region's zone that can carry the
+// parcel, with their prices. Regions without a zone get no options.
+func (s *shippingService) Options(region string, parcel Parcel) ([]Option, error) {
+	zone, err := s.zoneFor(region)
+	if err != nil {
+		return []Option{}, nil
+	}
+
+	options := []Option{}
+	for i := range zone.Methods {
+		method := &zone.Methods[i]
+		if !method.Active {
+			continue
+		}
+		if cost, ok := method.cost(parcel); ok {
+			options = append(options, Option{MethodID: method.ID, Name: method.Name, Zone: zone.Name, Cost: cost})
+		}
+	}
+	return options, nil
+}
+
+// Price prices one method for the parcel, checking that it ships to the region
+func (s *shippingService) Price(methodID int, region string, parcel Parcel) (*Option, error) {
+	zone, err := s.zoneFor(region)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no shipping to %s", ErrMethodUnavailable, NormalizeRegion(region))
+	}
+
+	for i := range zone.Methods {
+		method := &zone.Methods[i]
+		if method.ID != methodID {
+			continue
+		}
+		if !method.Active {
+			break
+		}
+		cost, ok := method.cost(parcel)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s does not carry %.2f kg", ErrMethodUnavailable, method.Name, parcel.Weight)
+		}
+		return &Option{MethodID: method.ID, Name: method.Name, Zone: zone.Name, Cost: cost}, nil
+	}
+	return nil, fmt.Errorf("%w: method %d does not ship to %s", ErrMethodUnavailable, methodID, NormalizeRegion(region))
+}
+
+// zoneFor finds the zone listing the region, or the fallback zone
+func (s *shippingService) zoneFor(region string) (*Zone, error) {
+	zone, err := s.repo.FindZoneByRegion(NormalizeRegion(region))
+	if err == nil {
+		return zone, nil
+	}
+	return s.repo.FindFallbackZone()
+}
+
+// zoneRegions normalizes the regions for a zone and checks that no other
+// zone already lists them
+func (s *shippingService) zoneRegions(zoneID int, regions []string) ([]ZoneRegion, error) {
+	var result []ZoneRegion
+	seen := make(map[string]bool)
+	for _, region := range regions {
+		region = NormalizeRegion(region)
+		if region == "" || seen[region] {
+			continue
+		}
+		seen[region] = true
+
+		if existing, err := s.repo.FindZoneByRegion(region); err == nil && existing.ID != zoneID {
+			return nil, fmt.Errorf("region %s already belongs to zone %s", region, existing.Name)
+		}
+		result = append(result, ZoneRegion{Region: region})
+	}
+	return result, nil
+}
+
+// cost prices the parcel, reporting false when it is heavier than every
+// weight bracket. Rates must be sorted by MaxWeight.
+func (m *Method) cost(parcel Parcel) (money.Money, bool) {
+	cost := m.FlatFee
+	if len(m.Rates) > 0 {
+		var bracket *WeightRate
+		for i := range m.Rates {
+			if parcel.Weight <= m.Rates[i].MaxWeight {
+				bracket = &m.Rates[i]
+				break
+			}
+		}
+		if bracket == nil {
+			return money.Money{}, false
+		}
+		cost = cost.Add(bracket.Price)
+	}
+
+	if m.FreeThreshold.IsPositive() && parcel.Subtotal.Cmp(m.FreeThreshold) >= 0 {
+		return money.Zero(cost.Currency), true
+	}
+	return cost, true
+}
+
+func methodFromRequest(req MethodRequest) (*Method, error) {
+	method := &Method{
+		Name:          strings.TrimSpace(req.Name),
+		FlatFee:       req.FlatFee.OrDefault(),
+		FreeThreshold: req.FreeThreshold.OrDefault(),
+		Active:        req.Active == nil || *req.Active,
+	}
+	amounts := []money.Money{method.FlatFee, method.FreeThreshold}
+	for _, rate := range req.Rates {
+		price := rate.Price.OrDefault()
+		method.Rates = append(method.Rates, WeightRate{MaxWeight: rate.MaxWeight, Price: price})
+		amounts = append(amounts, price)
+	}
+
+	for _, amount := range amounts {
+		if amount.IsNegative() || amount.Currency != money.DefaultCurrency() {
+			return nil, fmt.Errorf("fees must be zero or more in %s", money.DefaultCurrency())
+		}
+	}
+	return method, nil
+}
+
+// NormalizeRegion makes regions case-insensitive, matching tax regions
+func NormalizeRegion(region string) string {
+	return strings.ToUpper(strings.TrimSpace(region))
+}
@@ -0,0 +1,110 @@
+package tax
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxHandler struct {
+	service TaxService
+}
+
+func NewTaxHandler(service TaxService) *TaxHandler {
+	return &TaxHandler{service: service}
+}
+
+// CreateTaxRule creates a new tax rule (admin only)
+func (h *TaxHandler) CreateTaxRule(c *gin.Context) {
+	var req TaxRuleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	rule, err := h.service.CreateTaxRule(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tax rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetAllTaxRules retrieves all tax rules (admin only)
+func (h *TaxHandler) GetAllTaxRules(c *gin.Context) {
+	rules, err := h.service.GetAllTaxRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tax rules"})
+		return
+	}
+
+	if len(rules) == 0 {
+		c.JSON(http.StatusOK, []TaxRule{})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetTaxRuleByID retrieves a tax rule (admin only)
+func (h *TaxHandler) GetTaxRuleByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rule ID"})
+		return
+	}
+
+	rule, err := h.service.GetTaxRuleByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// UpdateTaxRule replaces a tax rule's settings (admin only)
+func (h *TaxHandler) UpdateTaxRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rule ID"})
+		return
+	}
+
+	var req TaxRuleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	rule, err := h.service.UpdateTaxRule(id, req)
+	if errors.Is(err, ErrTaxRuleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax rule not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tax rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteTaxRule deletes a tax rule (admin only)
+func (h *TaxHandler) DeleteTaxRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rule ID"})
+		return
+	}
+
+	if err := h.service.DeleteTaxRule(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tax rule deleted successfully"})
+}
@@ -0,0 +1,49 @@
+package tax
+
+import (
+	"time"
+
+	"mini-ecommerce/pkg/money"
+)
+
+// TaxRule charges Rate percent on lines of a tax class shipped to a region.
+// An empty TaxClass or Region matches any; when several rules match a line
+// the most specific one is used.
+type TaxRule struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`                   // shown in the order's tax breakdown, e.g. "VAT"
+	TaxClass  string    `json:"tax_class" gorm:"index"` // matches product tax_class
+	Region    string    `json:"region" gorm:"index"`    // matches the order's shipping_region
+	Rate      float64   `json:"rate"`                   // percent
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TaxRuleRequest struct {
+	Name     string  `json:"name" binding:"required"`
+	TaxClass string  `json:"tax_class"`
+	Region   string  `json:"region"`
+	Rate     float64 `json:"rate" binding:"gte=0,lte=100"`
+	Active   *bool   `json:"active"` // defaults to true
+}
+
+// Line is an order line being taxed
+type Line struct {
+	TaxClass string
+	Amount   money.Money // after discounts
+}
+
+// Breakdown is the tax charged under one rule
+type Breakdown struct {
+	Rule    TaxRule
+	Taxable money.Money
+	Amount  money.Money
+}
+
+// Result is the tax on a set of lines
+type Result struct {
+	LineTaxes []money.Money // same order as the lines that were taxed
+	Breakdown []Breakdown
+	Total     money.Money
+}
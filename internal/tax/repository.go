@@ -0,0 +1,70 @@
+package tax
+
+import "gorm.io/gorm"
+
+type TaxRuleRepository interface {
+	Create(rule *TaxRule) error
+	FindAll() ([]TaxRule, error)
+	FindActive() ([]TaxRule, error)
+	FindByID(id int) (*TaxRule, error)
+	Update(id int, rule *TaxRule) error
+	Delete(id int) error
+}
+
+type taxRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRuleRepository(db *gorm.DB) TaxRuleRepository {
+	return &taxRuleRepository{db: db}
+}
+
+func (r *taxRuleRepository) Create(rule *TaxRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *taxRuleRepository) FindAll() ([]TaxRule, error) {
+	var rules []TaxRule
+	err := r.db.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *taxRuleRepository) FindActive() ([]TaxRule, error) {
+	var rules []TaxRule
+	err := r.db.Where("active = ?", true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *taxRuleRepository) FindByID(id int) (*TaxRule, error) {
+	var rule TaxRule
+	err := r.db.First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Update saves every rule field, including zero values
+func (r *taxRuleRepository) Update(id int, rule *TaxRule) error {
+	result := r.db.Model(&TaxRule{}).Where("id = ?", id).
+		Select("*").Omit("id", "created_at").
+		Updates(rule)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *taxRuleRepository) Delete(id int) error {
+	result := r.db.Delete(&TaxRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
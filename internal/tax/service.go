@@ -0,0 +1,152 @@
+package tax
+
+import (
+	"errors"
+	"strings"
+
+	"mini-ecommerce/pkg/money"
+)
+
+var ErrTaxRuleNotFound = errors.New("tax rule not found")
+
+type TaxService interface {
+	CreateTaxRule(req TaxRuleRequest) (*TaxRule, error)
+	GetAllTaxRules() ([]TaxRule, error)
+	GetTaxRuleByID(id int) (*TaxRule, error)
+	UpdateTaxRule(id int, req TaxRuleRequest) (*TaxRule, error)
+	DeleteTaxRule(id int) error
+	Calculate(region string, lines []Line) (*Result, error)
+}
+
+type taxService struct {
+	repo TaxRuleRepository
+}
+
+func NewTaxService(repo TaxRuleRepository) TaxService {
+	return &taxService{repo: repo}
+}
+
+func (s *taxService) CreateTaxRule(req TaxRuleRequest) (*TaxRule, error) {
+	rule := ruleFromRequest(req)
+	err := s.repo.Create(rule)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *taxService) GetAllTaxRules() ([]TaxRule, error) {
+	return s.repo.FindAll()
+}
+
+func (s *taxService) GetTaxRuleByID(id int) (*TaxRule, error) {
+	rule, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, ErrTaxRuleNotFound
+	}
+	return rule, nil
+}
+
+func (s *taxService) UpdateTaxRule(id int, req TaxRuleRequest) (*TaxRule, error) {
+	if err := s.repo.Update(id, ruleFromRequest(req)); err != nil {
+		return nil, ErrTaxRuleNotFound
+	}
+	return s.repo.FindByID(id)
+}
+
+func (s *taxService) DeleteTaxRule(id int) error {
+	if err := s.repo.Delete(id); err != nil {
+		return ErrTaxRuleNotFound
+	}
+	return nil
+}
+
+// Calculate taxes each line under the most specific active rule for its tax
+// class and the region. Lines no rule matches are not taxed.
+func (s *taxService) Calculate(region string, lines []Line) (*Result, error) {
+	rules, err := s.repo.FindActive()
+	if err != nil {
+		return nil, err
+	}
+
+	region = NormalizeCode(region)
+	currency := money.DefaultCurrency()
+	if len(lines) > 0 {
+		currency = lines[0].Amount.Currency
+	}
+	result := &Result{
+		LineTaxes: make([]money.Money, len(lines)),
+		Total:     money.Zero(currency),
+	}
+	byRule := make(map[int]int) // rule ID -> index in result.Breakdown
+	for i, line := range lines {
+		result.LineTaxes[i] = money.Zero(currency)
+		rule := matchRule(rules, NormalizeCode(line.TaxClass), region)
+		if rule == nil || rule.Rate == 0 {
+			continue
+		}
+
+		amount := line.Amount.Percent(rule.Rate)
+		result.LineTaxes[i] = amount
+		result.Total = result.Total.Add(amount)
+
+		idx, ok := byRule[rule.ID]
+		if !ok {
+			idx = len(result.Breakdown)
+			byRule[rule.ID] = idx
+			result.Breakdown = append(result.Breakdown, Breakdown{
+				Rule:    *rule,
+				Taxable: money.Zero(currency),
+				Amount:  money.Zero(currency),
+			})
+		}
+		result.Breakdown[idx].Taxable = result.Breakdown[idx].Taxable.Add(line.Amount)
+		result.Breakdown[idx].Amount = result.Breakdown[idx].Amount.Add(amount)
+	}
+	return result, nil
+}
+
+// matchRule picks the rule for a tax class and region. A rule naming both
+// beats one naming only the class, which beats one naming only the region,
+// which beats a catch-all rule.
+func matchRule(rules []TaxRule, taxClass, region string) *TaxRule {
+	var best *TaxRule
+	bestScore := -1
+	for i := range rules {
+		rule := &rules[i]
+		score := 0
+		switch rule.TaxClass {
+		case "":
+		case taxClass:
+			score += 2
+		default:
+			continue
+		}
+		switch rule.Region {
+		case "":
+		case region:
+			score++
+		default:
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = rule, score
+		}
+	}
+	return best
+}
+
+func ruleFromRequest(req TaxRuleRequest) *TaxRule {
+	return &TaxRule{
+		Name:     strings.TrimSpace(req.Name),
+		TaxClass: NormalizeCode(req.TaxClass),
+		Region:   NormalizeCode(req.Region),
+		Rate:     req.Rate,
+		Active:   req.Active == nil || *req.Active,
+	}
+}
+
+// NormalizeCode makes tax classes and regions case-insensitive
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -1,9 +1,12 @@
 package user
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"mini-ecommerce/pkg/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -65,7 +68,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 	})
 }
 
-// GetProfile retrieves user profile
+// GetProfile retrieves a user profile (the user themselves or an admin)
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -73,6 +76,102 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
+	if !middleware.IsOwnerOrAdmin(c, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this profile"})
+		return
+	}
+
+	h.getProfile(c, id)
+}
+
+// UpdateProfile replaces a user profile (the user themselves or an admin)
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if !middleware.IsOwnerOrAdmin(c, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this profile"})
+		return
+	}
+
+	h.updateProfile(c, id)
+}
+
+// PatchProfile changes the fields of a user's profile given in a JSON Merge
+// Patch (the user themselves or an admin)
+func (h *UserHandler) PatchProfile(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if !middleware.IsOwnerOrAdmin(c, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this profile"})
+		return
+	}
+
+	h.patchProfile(c, id)
+}
+
+// GetMe retrieves the logged-in user's profile
+func (h *UserHandler) GetMe(c *gin.Context) {
+	h.getProfile(c, c.GetInt("userID"))
+}
+
+// UpdateMe replaces the logged-in user's profile
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	h.updateProfile(c, c.GetInt("userID"))
+}
+
+// PatchMe changes the fields of the logged-in user's profile given in a JSON
+// Merge Patch
+func (h *UserHandler) PatchMe(c *gin.Context) {
+	h.patchProfile(c, c.GetInt("userID"))
+}
+
+// ChangePassword changes the logged-in user's password
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.service.ChangePassword(c.GetInt("userID"), req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// DeleteMe deletes the logged-in user's account after checking their
+// password. Their orders are kept.
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := h.service.DeleteAccount(c.GetInt("userID"), req)
+	if errors.Is(err, ErrWrongPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+func (h *UserHandler) getProfile(c *gin.Context, id int) {
 	user, err := h.service.GetUserByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -91,21 +190,37 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, profile)
 }
 
-// UpdateProfile updates user profile
-func (h *UserHandler) UpdateProfile(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+func (h *UserHandler) updateProfile(c *gin.Context, id int) {
+	var req UserUpdateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	user, err := h.service.UpdateUser(id, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	var req UserUpdateRequest
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"user":    user.ToResponse(),
+	})
+}
+
+func (h *UserHandler) patchProfile(c *gin.Context, id int) {
+	var req UserPatchRequest
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	user, err := h.service.UpdateUser(id, req)
+	user, err := h.service.PatchUser(id, req)
+	if errors.Is(err, ErrInvalidPatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -134,7 +249,7 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, responses)
 }
 
-// DeleteUser deletes a user (admin only)
+// DeleteUser deletes a user, keeping their orders (admin only)
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -1,6 +1,12 @@
 package user
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"mini-ecommerce/pkg/patch"
+)
 
 type User struct {
 	ID        int       `json:"id" gorm:"primaryKey"`
@@ -11,6 +17,10 @@ type User struct {
 	Address   string    `json:"address"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set when the account is deleted. The row is kept, with its
+	// personal details cleared, so that orders, returns and coupon
+	// redemptions still refer to it.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // UserResponse - safe response without password hash
@@ -35,10 +45,29 @@ type UserLoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// UserUpdateRequest replaces a user's profile
 type UserUpdateRequest struct {
-	Name    string `json:"name"`
-	Phone   string `json:"phone"`
-	Address string `json:"address"`
+	Name    string `json:"name" binding:"required"`
+	Phone   string `json:"phone" binding:"required"`
+	Address string `json:"address" binding:"required"`
+}
+
+// UserPatchRequest is a JSON Merge Patch of a user's profile. None of the
+// fields can be null or empty.
+type UserPatchRequest struct {
+	Name    patch.Field[string] `json:"name"`
+	Phone   patch.Field[string] `json:"phone"`
+	Address patch.Field[string] `json:"address"`
+}
+
+// DeleteAccountRequest confirms deleting the logged-in user's account
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
 
 // ToResponse converts User to UserResponse (removes password)
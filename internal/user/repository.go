@@ -1,13 +1,17 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
 
 type UserRepository interface {
 	Create(user *User) error
 	FindByEmail(email string) (*User, error)
 	FindByID(id int) (*User, error)
 	Update(id int, user *User) error
-	Delete(id int) error
+	Anonymize(id int) error
 	GetAll() ([]User, error)
 }
 
@@ -45,8 +49,25 @@ func (r *userRepository) Update(id int, user *User) error {
 	return r.db.Model(&User{}).Where("id = ?", id).Updates(user).Error
 }
 
-func (r *userRepository) Delete(id int) error {
-	return r.db.Delete(&User{}, id).Error
+// Anonymize clears a user's personal details and soft-deletes them. The
+// email is replaced so that it can be registered again.
+func (r *userRepository) Anonymize(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"name":     "Deleted user",
+			"email":    fmt.Sprintf("deleted-%d@deleted.invalid", id),
+			"phone":    "",
+			"password": "",
+			"address":  "",
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Delete(&User{}, id).Error
+	})
 }
 
 func (r *userRepository) GetAll() ([]User, error) {
@@ -2,7 +2,17 @@ package user
 
 import (
 	"errors"
+	"fmt"
 	"mini-ecommerce/pkg/middleware"
+	"mini-ecommerce/pkg/validation"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	// ErrInvalidPatch is returned for patches that leave a profile invalid
+	ErrInvalidPatch  = errors.New("invalid patch")
+	ErrWrongPassword = errors.New("password is incorrect")
 )
 
 type UserService interface {
@@ -10,6 +20,9 @@ type UserService interface {
 	Login(req UserLoginRequest) (map[string]interface{}, error)
 	GetUserByID(id int) (*User, error)
 	UpdateUser(id int, req UserUpdateRequest) (*User, error)
+	PatchUser(id int, req UserPatchRequest) (*User, error)
+	ChangePassword(id int, req ChangePasswordRequest) error
+	DeleteAccount(id int, req DeleteAccountRequest) error
 	DeleteUser(id int) error
 	GetAllUsers() ([]User, error)
 }
@@ -75,21 +88,16 @@ func (s *userService) GetUserByID(id int) (*User, error) {
 	return s.repo.FindByID(id)
 }
 
+// UpdateUser replaces a user's name, phone and address
 func (s *userService) UpdateUser(id int, req UserUpdateRequest) (*User, error) {
 	user, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
-	if req.Name != "" {
-		user.Name = req.Name
-	}
-	if req.Phone != "" {
-		user.Phone = req.Phone
-	}
-	if req.Address != "" {
-		user.Address = req.Address
-	}
+	user.Name = req.Name
+	user.Phone = req.Phone
+	user.Address = req.Address
 
 	err = s.repo.Update(id, user)
 	if err != nil {
@@ -99,8 +107,62 @@ func (s *userService) UpdateUser(id int, req UserUpdateRequest) (*User, error) {
 	return s.repo.FindByID(id)
 }
 
+// PatchUser applies a JSON Merge Patch to a user's profile
+func (s *userService) PatchUser(id int, req UserPatchRequest) (*User, error) {
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if req.Name.Null || req.Phone.Null || req.Address.Null {
+		return nil, fmt.Errorf("%w: name, phone and address cannot be null", ErrInvalidPatch)
+	}
+
+	merged := UserUpdateRequest{Name: user.Name, Phone: user.Phone, Address: user.Address}
+	req.Name.Apply(&merged.Name)
+	req.Phone.Apply(&merged.Phone)
+	req.Address.Apply(&merged.Address)
+	if err := binding.Validator.ValidateStruct(&merged); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, validation.Describe(err))
+	}
+	return s.UpdateUser(id, merged)
+}
+
+func (s *userService) ChangePassword(id int, req ChangePasswordRequest) error {
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	// Verify current password using bcrypt
+	if !middleware.VerifyPassword(user.Password, req.CurrentPassword) {
+		return errors.New("current password is incorrect")
+	}
+
+	hashedPassword, err := middleware.HashPassword(req.NewPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	return s.repo.Update(id, &User{Password: hashedPassword})
+}
+
+// DeleteAccount deletes the user's own account once they confirm their
+// password
+func (s *userService) DeleteAccount(id int, req DeleteAccountRequest) error {
+	user, err := s.repo.FindByID(id)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !middleware.VerifyPassword(user.Password, req.Password) {
+		return ErrWrongPassword
+	}
+	return s.repo.Anonymize(id)
+}
+
+// DeleteUser deletes an account by clearing its personal details. Its
+// orders, returns and coupon redemptions are kept.
 func (s *userService) DeleteUser(id int) error {
-	return s.repo.Delete(id)
+	return s.repo.Anonymize(id)
 }
 
 func (s *userService) GetAllUsers() ([]User, error) {
@@ -6,11 +6,13 @@ import (
 	"mini-ecommerce/config"
 	database "mini-ecommerce/db"
 	"mini-ecommerce/internal/router"
+	"mini-ecommerce/pkg/money"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
+	money.SetDefaultCurrency(cfg.Currency)
 
 	// Connect to database
 	db := database.Connect(cfg)
@@ -21,7 +23,7 @@ func main() {
 	}
 
 	// Setup router with database
-	r := router.SetupRouter(db)
+	r := router.SetupRouter(db, cfg)
 
 	log.Println("Server running on port", cfg.Port)
 	r.Run(":" + cfg.Port)
@@ -0,0 +1,107 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	// Decoders for the accepted upload formats
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUnsupportedImage is returned for data that is not a JPEG, PNG, GIF or
+// WebP image, or that is too large to decode safely
+var ErrUnsupportedImage = errors.New("unsupported image")
+
+// jpegQuality is used for every JPEG thumbnail
+const jpegQuality = 85
+
+// formats maps the format names reported by the decoders to MIME types
+var formats = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// extensions are the file extensions for each MIME type
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Decode reads an image and reports its MIME type. Images of more than
+// maxPixels are refused before their pixels are decoded.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	contentType, ok := formats[format]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedImage, format)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d is too large", ErrUnsupportedImage, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	return img, contentType, nil
+}
+
+// Extension returns the file extension for a MIME type returned by Decode
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// ThumbnailType is the MIME type thumbnails of contentType are written in.
+// PNG keeps the transparency of PNG and GIF images; everything else is JPEG.
+func ThumbnailType(contentType string) string {
+	if contentType == "image/png" || contentType == "image/gif" {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// Fit scales img down to fit within a size x size square, keeping its
+// aspect ratio. Images that already fit are returned unchanged.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	if width >= height {
+		width, height = size, max(1, height*size/width)
+	} else {
+		width, height = max(1, width*size/height), size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// Encode writes img as image/jpeg or image/png
+func Encode(w io.Writer, img image.Image, contentType string) error {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		return png.Encode(w, img)
+	default:
+		return fmt.Errorf("%w: cannot encode %s", ErrUnsupportedImage, contentType)
+	}
+}
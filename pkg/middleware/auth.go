@@ -85,3 +85,25 @@ func UserMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// GetClaims returns the token claims stored in the context by AuthMiddleware
+func GetClaims(c *gin.Context) (*Claims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
+
+// IsOwnerOrAdmin reports whether the caller is the given user or holds an admin token
+func IsOwnerOrAdmin(c *gin.Context, userID int) bool {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return false
+	}
+	if claims.Type == "admin" {
+		return true
+	}
+	return claims.Type == "user" && claims.ID == userID
+}
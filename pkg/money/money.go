@@ -0,0 +1,274 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in a currency, held in minor units (e.g. paisa,
+// cents). Every currency is treated as having two decimal places.
+//
+// Store it with `gorm:"embedded;embeddedPrefix:price_"`, which maps to a
+// price_amount BIGINT and a price_currency column. In JSON it is written as
+// {"amount": "150.00", "currency": "BDT"} and read from that object, a
+// decimal string or a plain number in the default currency.
+type Money struct {
+	Amount   int64  `gorm:"not null;default:0"` // minor units
+	Currency string `gorm:"size:3"`             // ISO 4217 code
+}
+
+var (
+	// ErrInvalidAmount is returned when an amount cannot be parsed exactly
+	ErrInvalidAmount = errors.New("invalid money amount")
+	// ErrInvalidCurrency is returned for currencies that are not a
+	// three-letter ISO 4217 code
+	ErrInvalidCurrency = errors.New("invalid currency")
+)
+
+var defaultCurrency = "BDT"
+
+// SetDefaultCurrency sets the currency used for amounts given without one.
+// Call it once at startup.
+func SetDefaultCurrency(code string) {
+	if code = normalizeCurrency(code); code != "" {
+		defaultCurrency = code
+	}
+}
+
+// DefaultCurrency returns the currency used for amounts given without one
+func DefaultCurrency() string {
+	return defaultCurrency
+}
+
+// New returns amount minor units of currency
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: normalizeCurrency(currency)}
+}
+
+// Zero returns no money in currency
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Parse reads a decimal such as "150", "150.5" or "-3.20" in currency. More
+// than two decimal places is an error rather than being rounded.
+func Parse(s string, currency string) (Money, error) {
+	if !isCurrencyCode(normalizeCurrency(currency)) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > 2 || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || major > math.MaxInt64/100-1 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	minor, _ := strconv.ParseInt(frac, 10, 64)
+
+	amount := major*100 + minor
+	if negative {
+		amount = -amount
+	}
+	return New(amount, currency), nil
+}
+
+// MustParse is Parse for constants; it panics on error
+func MustParse(s string, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Decimal formats the amount without the currency, e.g. "150.00"
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency()
+}
+
+// OrDefault puts m in the default currency when it has none, as when a JSON
+// field was left out
+func (m Money) OrDefault() Money {
+	if m.Currency == "" {
+		m.Currency = defaultCurrency
+	}
+	return m
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// SameCurrency reports whether m and o can be combined. A currency-less
+// zero value combines with anything.
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == "" || o.Currency == "" || m.Currency == o.Currency
+}
+
+// Add returns m + o. Like the other arithmetic it panics if the currencies
+// differ; check SameCurrency on untrusted input first.
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.match(o)}
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.match(o)}
+}
+
+// Mul returns m * n
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Percent returns rate percent of m, rounded half away from zero to the minor unit
+func (m Money) Percent(rate float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * rate / 100)), Currency: m.Currency}
+}
+
+// MulFrac returns m * num / den, rounded half away from zero to the minor unit
+func (m Money) MulFrac(num, den int64) Money {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	d := big.NewInt(den)
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+
+	// Round half away from zero: bump q when the remainder is at least half of d
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		if (n.Sign() < 0) != (d.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Money{Amount: q.Int64(), Currency: m.Currency}
+}
+
+// Cmp compares m with o, returning -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	m.match(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Min returns the smaller of m and o
+func Min(m, o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return m
+	}
+	return o
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.currency()})
+}
+
+// UnmarshalJSON accepts {"amount": "150.00", "currency": "BDT"}, "150.00" or
+// 150. Amounts without a currency are in the default currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var obj struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		data = obj.Amount
+	}
+
+	text := string(data)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	currency := obj.Currency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	parsed, err := Parse(text, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return defaultCurrency
+	}
+	return m.Currency
+}
+
+func (m Money) match(o Money) string {
+	if !m.SameCurrency(o) {
+		panic(fmt.Sprintf("money: currency mismatch %s and %s", m.Currency, o.Currency))
+	}
+	if m.Currency == "" {
+		return o.Currency
+	}
+	return m.Currency
+}
+
+func normalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,240 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{"whole", "150", "BDT", New(15000, "BDT"), nil},
+		{"one decimal", "150.5", "BDT", New(15050, "BDT"), nil},
+		{"two decimals", "150.05", "BDT", New(15005, "BDT"), nil},
+		{"trailing point", "7.", "BDT", New(700, "BDT"), nil},
+		{"spaces", "  2.50 ", "BDT", New(250, "BDT"), nil},
+		{"negative", "-3.20", "BDT", New(-320, "BDT"), nil},
+		{"negative below one", "-0.05", "BDT", New(-5, "BDT"), nil},
+		{"plus sign", "+1.10", "BDT", New(110, "BDT"), nil},
+		{"lower-case currency", "1", "usd", New(100, "USD"), nil},
+		{"too many decimals", "1.234", "BDT", Money{}, ErrInvalidAmount},
+		{"too many zero decimals", "1.000", "BDT", Money{}, ErrInvalidAmount},
+		{"no whole part", ".5", "BDT", Money{}, ErrInvalidAmount},
+		{"empty", "", "BDT", Money{}, ErrInvalidAmount},
+		{"sign only", "-", "BDT", Money{}, ErrInvalidAmount},
+		{"two signs", "--1", "BDT", Money{}, ErrInvalidAmount},
+		{"thousands separator", "1,000", "BDT", Money{}, ErrInvalidAmount},
+		{"exponent", "1e3", "BDT", Money{}, ErrInvalidAmount},
+		{"letters", "ten", "BDT", Money{}, ErrInvalidAmount},
+		{"overflow", "92233720368547758", "BDT", Money{}, ErrInvalidAmount},
+		{"currency name", "1", "dollars", Money{}, ErrInvalidCurrency},
+		{"short currency", "1", "US", Money{}, ErrInvalidCurrency},
+		{"currency with digits", "1", "US1", Money{}, ErrInvalidCurrency},
+		{"no currency", "1", "", Money{}, ErrInvalidCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.currency)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q, %q) error = %v, want %v", tt.input, tt.currency, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q, %q) = %#v, want %#v", tt.input, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{150, "1.50"},
+		{15000, "150.00"},
+		{-5, "-0.05"},
+		{-320, "-3.20"},
+		{123456789, "1234567.89"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.amount, "BDT").Decimal(); got != tt.want {
+			t.Errorf("Decimal() of %d = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  string
+	}{
+		{"amount and currency", New(15000, "BDT"), `{"amount":"150.00","currency":"BDT"}`},
+		{"negative", New(-5, "usd"), `{"amount":"-0.05","currency":"USD"}`},
+		{"zero value is in the default currency", Money{}, `{"amount":"0.00","currency":"` + DefaultCurrency() + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.money)
+			if err != nil {
+				t.Fatalf("Marshal(%#v) error = %v", tt.money, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal(%#v) = %s, want %s", tt.money, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	def := DefaultCurrency()
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{"object", `{"amount": "150.00", "currency": "USD"}`, New(15000, "USD"), false},
+		{"object with a number", `{"amount": 1.25, "currency": "usd"}`, New(125, "USD"), false},
+		{"object without currency", `{"amount": "2.50"}`, New(250, def), false},
+		{"string", `"150.5"`, New(15050, def), false},
+		{"number", `150`, New(15000, def), false},
+		{"negative number", `-3.2`, New(-320, def), false},
+		{"output of MarshalJSON", `{"amount":"-0.05","currency":"USD"}`, New(-5, "USD"), false},
+		{"null leaves the value alone", `null`, New(99, "EUR"), false},
+		{"too many decimals", `"1.234"`, Money{}, true},
+		{"number with too many decimals", `0.001`, Money{}, true},
+		{"exponent", `1e2`, Money{}, true},
+		{"unknown currency", `{"amount": "1.00", "currency": "dollars"}`, Money{}, true},
+		{"not a number", `"ten"`, Money{}, true},
+		{"boolean", `true`, Money{}, true},
+		{"malformed object", `{"amount": }`, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(99, "EUR")
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, want error %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulFrac(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num, den int64
+		want     int64
+	}{
+		{"exact", 1000, 1, 4, 250},
+		{"rounds down below half", 1000, 1, 3, 333},
+		{"rounds up at half", 5, 1, 2, 3},
+		{"rounds up above half", 1000, 2, 3, 667},
+		{"negative rounds half away from zero", -5, 1, 2, -3},
+		{"negative denominator", 5, 1, -2, -3},
+		{"zero numerator", 1000, 0, 7, 0},
+		{"whole", 1999, 3, 3, 1999},
+		{"no overflow on large amounts", 9_000_000_000_000_000, 3, 4, 6_750_000_000_000_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount, "BDT").MulFrac(tt.num, tt.den)
+			if got.Amount != tt.want || got.Currency != "BDT" {
+				t.Errorf("MulFrac(%d, %d) of %d = %v, want %d BDT", tt.num, tt.den, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulFracPanicsOnZeroDenominator(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MulFrac(1, 0) did not panic")
+		}
+	}()
+	New(100, "BDT").MulFrac(1, 0)
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		rate   float64
+		want   int64
+	}{
+		{"exact", 10000, 15, 1500},
+		{"rounds down below half", 1033, 10, 103},
+		{"rounds up at half", 1035, 10, 104},
+		{"fractional rate", 10000, 7.5, 750},
+		{"negative rounds half away from zero", -1035, 10, -104},
+		{"zero rate", 10000, 0, 0},
+		{"whole amount", 999, 100, 999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount, "BDT").Percent(tt.rate)
+			if got.Amount != tt.want || got.Currency != "BDT" {
+				t.Errorf("Percent(%v) of %d = %v, want %d BDT", tt.rate, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddSub(t *testing.T) {
+	tests := []struct {
+		name     string
+		m, o     Money
+		sum      Money
+		diff     Money
+		mismatch bool
+	}{
+		{"same currency", New(1050, "BDT"), New(250, "BDT"), New(1300, "BDT"), New(800, "BDT"), false},
+		{"currency-less zero on the left", Money{}, New(250, "USD"), New(250, "USD"), New(-250, "USD"), false},
+		{"currency-less zero on the right", New(250, "USD"), Money{}, New(250, "USD"), New(250, "USD"), false},
+		{"different currencies", New(100, "BDT"), New(100, "USD"), Money{}, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, op := range []struct {
+				name string
+				fn   func(Money) Money
+				want Money
+			}{
+				{"Add", tt.m.Add, tt.sum},
+				{"Sub", tt.m.Sub, tt.diff},
+			} {
+				got, panicked := call(op.fn, tt.o)
+				if panicked != tt.mismatch {
+					t.Errorf("%s(%v, %v) panicked = %v, want %v", op.name, tt.m, tt.o, panicked, tt.mismatch)
+					continue
+				}
+				if !tt.mismatch && got != op.want {
+					t.Errorf("%s(%v, %v) = %v, want %v", op.name, tt.m, tt.o, got, op.want)
+				}
+			}
+		})
+	}
+}
+
+// call runs fn on o, reporting whether it panicked
+func call(fn func(Money) Money, o Money) (result Money, panicked bool) {
+	defer func() {
+		if recover() != nil {
+			panicked = true
+		}
+	}()
+	return fn(o), false
+}
@@ -0,0 +1,48 @@
+// Package patch reads JSON Merge Patch (RFC 7396) documents, where a member
+// left out of the document is unchanged, a null member is removed and any
+// other member is replaced.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Field is one member of a merge patch. Declare it without a pointer or
+// omitempty, so that decoding leaves absent members unset:
+//
+//	type PatchRequest struct {
+//		Name patch.Field[string] `json:"name"`
+//	}
+type Field[T any] struct {
+	Set   bool // the member was in the document, possibly as null
+	Null  bool // the member was null
+	Value T    // the new value when set and not null
+}
+
+// UnmarshalJSON records that the member was present, and whether it was null
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	var value T
+	f.Set = true
+	f.Null = bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+	if !f.Null {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+	f.Value = value
+	return nil
+}
+
+// HasValue reports whether the patch gives the member a new, non-null value
+func (f Field[T]) HasValue() bool {
+	return f.Set && !f.Null
+}
+
+// Apply sets *dst to the new value if there is one, or to the zero value if
+// the member is null, and leaves it alone if the member is absent
+func (f Field[T]) Apply(dst *T) {
+	if f.Set {
+		*dst = f.Value
+	}
+}
@@ -0,0 +1,81 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type doc struct {
+	Name  Field[string]   `json:"name"`
+	Count Field[int]      `json:"count"`
+	Tags  Field[[]string] `json:"tags"`
+}
+
+func TestFieldUnmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantSet   bool
+		wantNull  bool
+		wantValue string
+	}{
+		{"absent", `{}`, false, false, ""},
+		{"null", `{"name": null}`, true, true, ""},
+		{"null with spaces", `{"name":  null }`, true, true, ""},
+		{"set", `{"name": "apple"}`, true, false, "apple"},
+		{"set to empty", `{"name": ""}`, true, false, ""},
+		{"string null is a value", `{"name": "null"}`, true, false, "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d doc
+			if err := json.Unmarshal([]byte(tt.body), &d); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.body, err)
+			}
+			if d.Name.Set != tt.wantSet || d.Name.Null != tt.wantNull || d.Name.Value != tt.wantValue {
+				t.Errorf("Unmarshal(%s) = %+v, want Set=%v Null=%v Value=%q", tt.body, d.Name, tt.wantSet, tt.wantNull, tt.wantValue)
+			}
+			if got, want := d.Name.HasValue(), tt.wantSet && !tt.wantNull; got != want {
+				t.Errorf("HasValue() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFieldUnmarshalWrongType(t *testing.T) {
+	var d doc
+	if err := json.Unmarshal([]byte(`{"count": "three"}`), &d); err == nil {
+		t.Error("Unmarshal of a string into Field[int] succeeded")
+	}
+}
+
+func TestFieldApply(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"absent keeps the value", `{}`, []string{"red"}},
+		{"null clears it", `{"tags": null}`, nil},
+		{"set replaces it", `{"tags": ["green", "blue"]}`, []string{"green", "blue"}},
+		{"empty array replaces it", `{"tags": []}`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d doc
+			if err := json.Unmarshal([]byte(tt.body), &d); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.body, err)
+			}
+			tags := []string{"red"}
+			d.Tags.Apply(&tags)
+			if (tags == nil) != (tt.want == nil) || len(tags) != len(tt.want) {
+				t.Fatalf("Apply() left %#v, want %#v", tags, tt.want)
+			}
+			for i := range tags {
+				if tags[i] != tt.want[i] {
+					t.Errorf("Apply() left %#v, want %#v", tags, tt.want)
+				}
+			}
+		})
+	}
+}
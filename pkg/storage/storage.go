@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidKey is returned for keys that are not clean relative paths
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps uploaded files. Keys are slash-separated relative paths such
+// as "products/12/4f9c0e.jpg".
+type Storage interface {
+	// Save writes data under key, replacing any file already there
+	Save(key string, data io.Reader, contentType string) error
+	// Delete removes the file under key; a missing file is not an error
+	Delete(key string) error
+	// URL is where clients can fetch the file under key
+	URL(key string) string
+}
+
+// New returns the storage for the configured driver name. dir is where the
+// local driver keeps files, and baseURL is the URL they are served from.
+func New(driver string, dir string, baseURL string) (Storage, error) {
+	switch driver {
+	case "local":
+		return NewLocalStorage(dir, baseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
@@ -0,0 +1,41 @@
+// Package validation turns binding validation errors into messages fit to
+// show to API clients.
+package validation
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Describe turns binding validation errors into a short message such as
+// "name is required; weight must be greater than 0". Other errors are
+// returned as they are.
+func Describe(err error) string {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err.Error()
+	}
+	messages := make([]string, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		field := strings.ToLower(fe.Field())
+		switch fe.Tag() {
+		case "required":
+			messages[i] = field + " is required"
+		case "gt":
+			messages[i] = field + " must be greater than " + fe.Param()
+		case "gte":
+			messages[i] = field + " must be at least " + fe.Param()
+		case "min":
+			messages[i] = field + " must be at least " + fe.Param() + " characters"
+		case "email":
+			messages[i] = field + " must be an email address"
+		case "oneof":
+			messages[i] = field + " must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+		default:
+			messages[i] = field + " is invalid"
+		}
+	}
+	return strings.Join(messages, "; ")
+}
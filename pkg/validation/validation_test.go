@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type account struct {
+	Name     string  `validate:"required"`
+	Email    string  `validate:"required,email"`
+	Password string  `validate:"min=6"`
+	Role     string  `validate:"oneof=admin super_admin"`
+	Weight   float64 `validate:"gt=0"`
+	Stock    int     `validate:"gte=0"`
+	Code     string  `validate:"len=3"`
+}
+
+func TestDescribe(t *testing.T) {
+	valid := account{Name: "Ana", Email: "ana@example.com", Password: "secret", Role: "admin", Weight: 1, Code: "abc"}
+
+	tests := []struct {
+		name   string
+		modify func(a *account)
+		want   string
+	}{
+		{"required", func(a *account) { a.Name = "" }, "name is required"},
+		{"email", func(a *account) { a.Email = "ana" }, "email must be an email address"},
+		{"min", func(a *account) { a.Password = "abc" }, "password must be at least 6 characters"},
+		{"oneof", func(a *account) { a.Role = "owner" }, "role must be one of admin, super_admin"},
+		{"gt", func(a *account) { a.Weight = 0 }, "weight must be greater than 0"},
+		{"gte", func(a *account) { a.Stock = -1 }, "stock must be at least 0"},
+		{"other tags", func(a *account) { a.Code = "abcd" }, "code is invalid"},
+		{"several fields", func(a *account) { a.Name = ""; a.Weight = 0 }, "name is required; weight must be greater than 0"},
+	}
+	validate := validator.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := valid
+			tt.modify(&a)
+			if got := Describe(validate.Struct(a)); got != tt.want {
+				t.Errorf("Describe() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeOtherErrors(t *testing.T) {
+	if got := Describe(errors.New("body is not JSON")); got != "body is not JSON" {
+		t.Errorf("Describe() = %q, want the error text", got)
+	}
+}
@@ -4,22 +4,45 @@
 CREATE TABLE IF NOT EXISTS products (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
-    price DECIMAL(10, 2) NOT NULL,
+    sku VARCHAR(100) NOT NULL DEFAULT '', -- optional; unique when set
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
     weight DECIMAL(10, 2) NOT NULL,
     colour VARCHAR(100),
     description TEXT NOT NULL,
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    tax_class VARCHAR(50) NOT NULL DEFAULT 'STANDARD',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    rating_average DECIMAL(3, 2) NOT NULL DEFAULT 0, -- approved reviews only
+    rating_count INTEGER NOT NULL DEFAULT 0,
+    search_vector TSVECTOR, -- name (weight A) and description (weight B), set by the app
+    status VARCHAR(20) NOT NULL DEFAULT 'published', -- draft, published, archived; the app creates drafts
+    publish_at TIMESTAMP, -- published products stay hidden until then
+    unpublish_at TIMESTAMP, -- and are hidden again from then
+    deleted_at TIMESTAMP -- set while the product is in the trash
 );
 
 -- Create index for faster queries
 CREATE INDEX IF NOT EXISTS idx_products_name ON products(name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku) WHERE sku <> '';
+CREATE INDEX IF NOT EXISTS idx_products_price_amount ON products(price_amount);
+CREATE INDEX IF NOT EXISTS idx_products_created_at ON products(created_at);
+CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_status ON products(status);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products(deleted_at);
 
 -- Insert sample data
-INSERT INTO products (name, price, weight, colour, description) VALUES
-('Apple', 150, 0.5, 'green', 'Fresh green apple'),
-('Orange', 200, 0.4, 'orange', 'Fresh orange fruit'),
-('Banana', 100, 0.3, 'yellow', 'Sweet yellow banana');
+INSERT INTO products (name, price_amount, weight, colour, description, stock) VALUES
+('Apple', 15000, 0.5, 'green', 'Fresh green apple', 100),
+('Orange', 20000, 0.4, 'orange', 'Fresh orange fruit', 80),
+('Banana', 10000, 0.3, 'yellow', 'Sweet yellow banana', 120);
+
+-- Index the sample products for full-text search
+UPDATE products SET search_vector =
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+WHERE search_vector IS NULL;
 
 -- View all products
 SELECT * FROM products;
@@ -4,33 +4,77 @@
 CREATE TABLE IF NOT EXISTS orders (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL,
-    product_id INTEGER NOT NULL,
-    quantity INTEGER NOT NULL DEFAULT 1,
-    total_price DECIMAL(10, 2) NOT NULL,
-    status VARCHAR(50) DEFAULT 'pending', -- pending, confirmed, delivered
+    subtotal_amount BIGINT NOT NULL DEFAULT 0,
+    subtotal_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    discount_amount BIGINT NOT NULL DEFAULT 0,
+    discount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    coupon_code VARCHAR(100),
+    shipping_region VARCHAR(50),
+    tax_total_amount BIGINT NOT NULL DEFAULT 0,
+    tax_total_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    shipping_method_id INTEGER,
+    shipping_method VARCHAR(100),
+    shipping_cost_amount BIGINT NOT NULL DEFAULT 0,
+    shipping_cost_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    total_price_amount BIGINT NOT NULL DEFAULT 0, -- subtotal - discount + tax_total + shipping_cost
+    total_price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    status VARCHAR(50) DEFAULT 'pending', -- pending, paid, confirmed, shipped, delivered, cancelled, returned, refunded
+    cancel_reason TEXT,
+    cancelled_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+    deleted_at TIMESTAMP, -- set when an admin purges the order
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
 -- Create indexes for faster queries
 CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
-CREATE INDEX IF NOT EXISTS idx_orders_product_id ON orders(product_id);
 CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders(deleted_at);
+
+-- Order line items (one row per product in an order)
+CREATE TABLE IF NOT EXISTS order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    variant_id INTEGER NOT NULL DEFAULT 0, -- 0 for products without variants
+    sku VARCHAR(100),
+    name VARCHAR(255) NOT NULL,
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    quantity INTEGER NOT NULL DEFAULT 1,
+    total_amount BIGINT NOT NULL DEFAULT 0,
+    total_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    discount_amount BIGINT NOT NULL DEFAULT 0,
+    discount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    tax_amount_amount BIGINT NOT NULL DEFAULT 0,
+    tax_amount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    -- products in orders are soft-deleted and cannot be purged
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
 
 -- Insert sample data
-INSERT INTO orders (user_id, product_id, quantity, total_price, status) VALUES
-(1, 1, 2, 300, 'pending'),
-(2, 2, 3, 600, 'confirmed'),
-(3, 3, 1, 100, 'delivered');
+INSERT INTO orders (user_id, subtotal_amount, total_price_amount, status) VALUES
+(1, 30000, 30000, 'pending'),
+(2, 60000, 60000, 'confirmed'),
+(3, 10000, 10000, 'delivered');
+
+INSERT INTO order_items (order_id, product_id, name, price_amount, quantity, total_amount) VALUES
+(1, 1, 'Apple', 15000, 2, 30000),
+(2, 2, 'Orange', 20000, 3, 60000),
+(3, 3, 'Banana', 10000, 1, 10000);
 
 -- View all orders
 SELECT * FROM orders;
 
 -- View user's orders
-SELECT o.id, o.user_id, u.name, u.email, o.product_id, p.name as product_name, 
-       o.quantity, o.total_price, o.status, o.created_at
+SELECT o.id, o.user_id, u.name, u.email, i.product_id, i.name as product_name,
+       i.quantity, i.total_amount / 100.0 AS total, o.total_price_amount / 100.0 AS total_price,
+       o.total_price_currency AS currency, o.status, o.created_at
 FROM orders o
 JOIN users u ON o.user_id = u.id
-JOIN products p ON o.product_id = p.id;
+JOIN order_items i ON i.order_id = o.id;
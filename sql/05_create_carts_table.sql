@@ -0,0 +1,33 @@
+-- Carts Table for Shopping Carts
+-- Each user has one cart; items keep only product and quantity,
+-- prices are looked up from products at read and checkout time
+
+CREATE TABLE IF NOT EXISTS carts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id SERIAL PRIMARY KEY,
+    cart_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    variant_id INTEGER NOT NULL DEFAULT 0, -- 0 for products without variants
+    quantity INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cart_id) REFERENCES carts(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+-- One row per product variant per cart
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cart_items_cart_product_variant ON cart_items(cart_id, product_id, variant_id);
+
+-- View cart contents with current prices
+SELECT c.user_id, i.product_id, p.name, p.price_amount / 100.0 AS price, i.quantity,
+       p.price_amount * i.quantity / 100.0 AS total, p.price_currency AS currency
+FROM carts c
+JOIN cart_items i ON i.cart_id = c.id
+JOIN products p ON p.id = i.product_id;
@@ -0,0 +1,22 @@
+-- Order Status History Table
+-- One row per order status change, including who made it and why
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    from_status VARCHAR(50), -- empty for the initial "order placed" entry
+    to_status VARCHAR(50) NOT NULL,
+    actor_type VARCHAR(20) NOT NULL, -- user, admin, system
+    actor_id INTEGER,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
+
+-- View the timeline of an order
+SELECT from_status, to_status, actor_type, actor_id, reason, created_at
+FROM order_status_history
+WHERE order_id = 1
+ORDER BY created_at, id;
@@ -0,0 +1,26 @@
+-- Payments Table
+-- One row per payment intent created with the payment provider
+
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    provider VARCHAR(50) NOT NULL, -- fake, ...
+    intent_id VARCHAR(255) NOT NULL UNIQUE,
+    client_secret VARCHAR(255),
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    refunded_amount BIGINT NOT NULL DEFAULT 0,
+    refunded_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    status VARCHAR(50) DEFAULT 'pending', -- pending, authorized, captured, failed, refunded
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+CREATE INDEX IF NOT EXISTS idx_payments_status ON payments(status);
+
+-- View payments with their orders
+SELECT p.id, p.order_id, o.status AS order_status, p.provider, p.amount / 100.0 AS amount, p.currency, p.status
+FROM payments p
+JOIN orders o ON o.id = p.order_id;
@@ -0,0 +1,55 @@
+-- Returns (RMA) Tables
+-- Customers open return requests for delivered orders; admins approve,
+-- reject and receive them. Approval creates the refund and restocks items.
+
+CREATE TABLE IF NOT EXISTS return_requests (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    status VARCHAR(50) DEFAULT 'requested', -- requested, approved, rejected, received
+    reason TEXT NOT NULL,
+    admin_note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    id SERIAL PRIMARY KEY,
+    return_request_id INTEGER NOT NULL,
+    order_item_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    variant_id INTEGER NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL,
+    reason TEXT,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (return_request_id) REFERENCES return_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    return_request_id INTEGER NOT NULL UNIQUE,
+    order_id INTEGER NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    status VARCHAR(50) DEFAULT 'pending', -- pending, processed, manual
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (return_request_id) REFERENCES return_requests(id),
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_return_requests_order_id ON return_requests(order_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_user_id ON return_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_status ON return_requests(status);
+CREATE INDEX IF NOT EXISTS idx_return_items_return_request_id ON return_items(return_request_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds(order_id);
+
+-- View open returns
+SELECT r.id, r.order_id, r.user_id, r.status, r.reason, r.created_at
+FROM return_requests r
+WHERE r.status IN ('requested', 'approved')
+ORDER BY r.created_at DESC;
@@ -0,0 +1,58 @@
+-- Coupons Tables
+-- Percentage or fixed coupons with minimum spend, usage limits, validity
+-- windows and optional product restrictions. Redemptions link coupons to orders.
+
+CREATE TABLE IF NOT EXISTS coupons (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(100) UNIQUE NOT NULL,
+    type VARCHAR(20) NOT NULL, -- percentage, fixed
+    value DECIMAL(5, 2) DEFAULT 0, -- percent, for percentage coupons
+    amount_amount BIGINT NOT NULL DEFAULT 0, -- for fixed coupons
+    amount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    min_spend_amount BIGINT NOT NULL DEFAULT 0,
+    min_spend_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    max_uses INTEGER DEFAULT 0, -- 0 = unlimited
+    per_user_limit INTEGER DEFAULT 0, -- 0 = unlimited
+    used_count INTEGER DEFAULT 0,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS coupon_products (
+    id SERIAL PRIMARY KEY,
+    coupon_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id SERIAL PRIMARY KEY,
+    coupon_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    order_id INTEGER NOT NULL,
+    discount_amount BIGINT NOT NULL DEFAULT 0,
+    discount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_products_coupon_id ON coupon_products(coupon_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_id ON coupon_redemptions(coupon_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_user_id ON coupon_redemptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_order_id ON coupon_redemptions(order_id);
+
+-- Sample coupon: 10% off orders of 500 or more, once per user
+INSERT INTO coupons (code, type, value, min_spend_amount, per_user_limit) VALUES
+('WELCOME10', 'percentage', 10, 50000, 1);
+
+-- View coupon usage
+SELECT c.code, c.used_count, c.max_uses, COUNT(r.id) AS redemptions, COALESCE(SUM(r.discount_amount), 0) / 100.0 AS total_discount
+FROM coupons c
+LEFT JOIN coupon_redemptions r ON r.coupon_id = c.id
+GROUP BY c.id;
@@ -0,0 +1,41 @@
+-- Tax Tables
+-- Admin-managed tax rules by product tax class and shipping region, and the
+-- tax breakdown stored on each order. The most specific matching rule wins.
+
+CREATE TABLE IF NOT EXISTS tax_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    tax_class VARCHAR(50), -- empty matches every tax class
+    region VARCHAR(50), -- empty matches every region
+    rate DECIMAL(5, 2) NOT NULL, -- percent
+    active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS order_taxes (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    tax_rule_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    rate DECIMAL(5, 2) NOT NULL,
+    taxable_amount BIGINT NOT NULL DEFAULT 0,
+    taxable_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_rules_tax_class ON tax_rules(tax_class);
+CREATE INDEX IF NOT EXISTS idx_tax_rules_region ON tax_rules(region);
+CREATE INDEX IF NOT EXISTS idx_order_taxes_order_id ON order_taxes(order_id);
+
+-- Sample rules: 15% VAT everywhere, 5% on reduced-rate goods
+INSERT INTO tax_rules (name, tax_class, region, rate) VALUES
+('VAT', '', '', 15),
+('Reduced VAT', 'REDUCED', '', 5);
+
+-- View tax collected per rule
+SELECT t.name, t.rate, SUM(t.taxable_amount) / 100.0 AS taxable, SUM(t.amount) / 100.0 AS collected
+FROM order_taxes t
+GROUP BY t.name, t.rate;
@@ -0,0 +1,64 @@
+-- Shipping Tables
+-- Admin-configured zones, their regions and shipping methods with weight
+-- based rate tables, flat fees and free-shipping thresholds.
+
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A zone without regions is the fallback for unlisted regions
+CREATE TABLE IF NOT EXISTS shipping_zone_regions (
+    id SERIAL PRIMARY KEY,
+    zone_id INTEGER NOT NULL,
+    region VARCHAR(50) UNIQUE NOT NULL,
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id SERIAL PRIMARY KEY,
+    zone_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    flat_fee_amount BIGINT NOT NULL DEFAULT 0,
+    flat_fee_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    free_threshold_amount BIGINT NOT NULL DEFAULT 0, -- 0 = never free
+    free_threshold_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+);
+
+-- Weight brackets: a parcel uses the first bracket with max_weight >= its weight
+CREATE TABLE IF NOT EXISTS shipping_rates (
+    id SERIAL PRIMARY KEY,
+    method_id INTEGER NOT NULL,
+    max_weight DECIMAL(10, 2) NOT NULL, -- in kg
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (method_id) REFERENCES shipping_methods(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_zone_regions_zone_id ON shipping_zone_regions(zone_id);
+CREATE INDEX IF NOT EXISTS idx_shipping_methods_zone_id ON shipping_methods(zone_id);
+CREATE INDEX IF NOT EXISTS idx_shipping_rates_method_id ON shipping_rates(method_id);
+
+-- Sample: Dhaka city and the rest of the country
+INSERT INTO shipping_zones (name) VALUES ('Dhaka'), ('Rest of Bangladesh');
+INSERT INTO shipping_zone_regions (zone_id, region) VALUES (1, 'BD-DHAKA');
+INSERT INTO shipping_methods (zone_id, name, flat_fee_amount, free_threshold_amount) VALUES
+(1, 'Standard', 6000, 100000),
+(2, 'Standard', 12000, 200000);
+INSERT INTO shipping_rates (method_id, max_weight, price_amount) VALUES
+(1, 1, 0), (1, 5, 4000), (1, 20, 10000),
+(2, 1, 0), (2, 5, 6000), (2, 20, 15000);
+
+-- View methods and rate tables per zone
+SELECT z.name AS zone, m.name AS method, m.flat_fee_amount / 100.0 AS flat_fee,
+       m.free_threshold_amount / 100.0 AS free_threshold, r.max_weight, r.price_amount / 100.0 AS price
+FROM shipping_zones z
+JOIN shipping_methods m ON m.zone_id = z.id
+LEFT JOIN shipping_rates r ON r.method_id = m.id
+ORDER BY z.id, m.id, r.max_weight;
@@ -0,0 +1,20 @@
+-- Product Variants Table
+-- Variants of a product, e.g. each colour and size of a shirt. A product
+-- with variants is sold only through them, and each keeps its own stock.
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    sku VARCHAR(100) UNIQUE NOT NULL,
+    attributes JSONB, -- e.g. {"colour": "red", "size": "M"}
+    attribute_key VARCHAR(255) NOT NULL, -- normalised attributes, unique per product
+    price_override_amount BIGINT NOT NULL DEFAULT 0, -- 0 = product price
+    price_override_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    weight_override DECIMAL(10, 2) NOT NULL DEFAULT 0, -- 0 = product weight
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_attributes ON product_variants(product_id, attribute_key);
@@ -0,0 +1,19 @@
+-- Product Images Table
+-- Gallery images of a product. The files (the original and its small,
+-- medium and large thumbnails) are kept in upload storage; only the
+-- original's storage key is saved here.
+
+CREATE TABLE IF NOT EXISTS product_images (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0, -- gallery order, from 0
+    key VARCHAR(255) NOT NULL, -- e.g. products/12/4f9c0e.jpg
+    content_type VARCHAR(50),
+    width INTEGER,
+    height INTEGER,
+    alt_text VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images(product_id);
@@ -0,0 +1,19 @@
+-- Product Price History Table
+-- One row per price a product has had. A new row is added whenever the price
+-- changes, ending the previous one; the current price has no effective_to.
+-- changed_by is the admin who set the price, or 0 for command-line imports
+-- and prices from before history was kept. Order items keep their own copy
+-- of the price paid.
+
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP,
+    changed_by INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_history_product ON product_price_history(product_id, effective_from);
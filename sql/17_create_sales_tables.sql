@@ -0,0 +1,45 @@
+-- Sales Tables
+-- Scheduled sales that lower prices between starts_at and ends_at, by a
+-- percentage, a fixed amount or to a set price. A sale covers the products in
+-- sale_products and every product in the categories in sale_categories or
+-- their subcategories. Cancelled sales keep their row with cancelled_at set.
+
+CREATE TABLE IF NOT EXISTS sales (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL, -- percentage, fixed, price
+    value DECIMAL(5, 2) DEFAULT 0, -- percent, for percentage sales
+    amount_amount BIGINT NOT NULL DEFAULT 0, -- for fixed and price sales
+    amount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    cancelled_at TIMESTAMP,
+    created_by INTEGER, -- admin ID
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_starts_at ON sales(starts_at);
+CREATE INDEX IF NOT EXISTS idx_sales_ends_at ON sales(ends_at);
+
+CREATE TABLE IF NOT EXISTS sale_products (
+    id SERIAL PRIMARY KEY,
+    sale_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    FOREIGN KEY (sale_id) REFERENCES sales(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sale_products_sale_id ON sale_products(sale_id);
+CREATE INDEX IF NOT EXISTS idx_sale_products_product_id ON sale_products(product_id);
+
+CREATE TABLE IF NOT EXISTS sale_categories (
+    id SERIAL PRIMARY KEY,
+    sale_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    FOREIGN KEY (sale_id) REFERENCES sales(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sale_categories_sale_id ON sale_categories(sale_id);
+CREATE INDEX IF NOT EXISTS idx_sale_categories_category_id ON sale_categories(category_id);
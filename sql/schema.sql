@@ -1,5 +1,6 @@
 -- Mini E-Commerce Database Schema
 -- Complete database setup for the project
+-- Money is stored as <name>_amount in minor units (15000 = 150.00) plus <name>_currency
 
 -- ============================================
 -- 1. ADMINS TABLE
@@ -23,15 +24,32 @@ CREATE INDEX IF NOT EXISTS idx_admins_email ON admins(email);
 CREATE TABLE IF NOT EXISTS products (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
-    price DECIMAL(10, 2) NOT NULL,
+    sku VARCHAR(100) NOT NULL DEFAULT '', -- optional; unique when set
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
     weight DECIMAL(10, 2) NOT NULL,
     colour VARCHAR(100),
     description TEXT NOT NULL,
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    tax_class VARCHAR(50) NOT NULL DEFAULT 'STANDARD',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    rating_average DECIMAL(3, 2) NOT NULL DEFAULT 0, -- approved reviews only
+    rating_count INTEGER NOT NULL DEFAULT 0,
+    search_vector TSVECTOR, -- name (weight A) and description (weight B), set by the app
+    status VARCHAR(20) NOT NULL DEFAULT 'published', -- draft, published, archived; the app creates drafts
+    publish_at TIMESTAMP, -- published products stay hidden until then
+    unpublish_at TIMESTAMP, -- and are hidden again from then
+    deleted_at TIMESTAMP -- set while the product is in the trash
 );
 
 CREATE INDEX IF NOT EXISTS idx_products_name ON products(name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku) WHERE sku <> '';
+CREATE INDEX IF NOT EXISTS idx_products_price_amount ON products(price_amount);
+CREATE INDEX IF NOT EXISTS idx_products_created_at ON products(created_at);
+CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_status ON products(status);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products(deleted_at);
 
 -- ============================================
 -- 3. USERS TABLE
@@ -44,7 +62,8 @@ CREATE TABLE IF NOT EXISTS users (
     password VARCHAR(255) NOT NULL,
     address TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP -- set when the account is deleted; its personal details are cleared
 );
 
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
@@ -55,19 +74,464 @@ CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE TABLE IF NOT EXISTS orders (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL,
+    subtotal_amount BIGINT NOT NULL DEFAULT 0,
+    subtotal_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    discount_amount BIGINT NOT NULL DEFAULT 0,
+    discount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    coupon_code VARCHAR(100),
+    shipping_region VARCHAR(50),
+    tax_total_amount BIGINT NOT NULL DEFAULT 0,
+    tax_total_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    shipping_method_id INTEGER,
+    shipping_method VARCHAR(100),
+    shipping_cost_amount BIGINT NOT NULL DEFAULT 0,
+    shipping_cost_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    total_price_amount BIGINT NOT NULL DEFAULT 0, -- subtotal - discount + tax_total + shipping_cost
+    total_price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    status VARCHAR(50) DEFAULT 'pending', -- pending, paid, confirmed, shipped, delivered, cancelled, returned, refunded
+    cancel_reason TEXT,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP, -- set when an admin purges the order
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders(deleted_at);
+
+-- ============================================
+-- 5. ORDER ITEMS TABLE
+-- ============================================
+CREATE TABLE IF NOT EXISTS order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
     product_id INTEGER NOT NULL,
+    variant_id INTEGER NOT NULL DEFAULT 0, -- 0 for products without variants
+    sku VARCHAR(100),
+    name VARCHAR(255) NOT NULL,
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
     quantity INTEGER NOT NULL DEFAULT 1,
-    total_price DECIMAL(10, 2) NOT NULL,
-    status VARCHAR(50) DEFAULT 'pending', -- pending, confirmed, delivered
+    total_amount BIGINT NOT NULL DEFAULT 0,
+    total_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    discount_amount BIGINT NOT NULL DEFAULT 0,
+    discount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    tax_amount_amount BIGINT NOT NULL DEFAULT 0,
+    tax_amount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    -- products in orders are soft-deleted and cannot be purged
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
+
+-- ============================================
+-- 6. CARTS TABLES
+-- ============================================
+CREATE TABLE IF NOT EXISTS carts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL UNIQUE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id SERIAL PRIMARY KEY,
+    cart_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    variant_id INTEGER NOT NULL DEFAULT 0, -- 0 for products without variants
+    quantity INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cart_id) REFERENCES carts(id) ON DELETE CASCADE,
     FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
 );
 
-CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
-CREATE INDEX IF NOT EXISTS idx_orders_product_id ON orders(product_id);
-CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cart_items_cart_product ON cart_items(cart_id, product_id);
+
+-- ============================================
+-- 7. ORDER STATUS HISTORY TABLE
+-- ============================================
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    actor_type VARCHAR(20) NOT NULL, -- user, admin, system
+    actor_id INTEGER,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
+
+-- ============================================
+-- 8. PAYMENTS TABLE
+-- ============================================
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    intent_id VARCHAR(255) NOT NULL UNIQUE,
+    client_secret VARCHAR(255),
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    refunded_amount BIGINT NOT NULL DEFAULT 0,
+    refunded_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    status VARCHAR(50) DEFAULT 'pending', -- pending, authorized, captured, failed, refunded
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+CREATE INDEX IF NOT EXISTS idx_payments_status ON payments(status);
+
+-- ============================================
+-- 9. RETURNS TABLES
+-- ============================================
+CREATE TABLE IF NOT EXISTS return_requests (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    status VARCHAR(50) DEFAULT 'requested', -- requested, approved, rejected, received
+    reason TEXT NOT NULL,
+    admin_note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    id SERIAL PRIMARY KEY,
+    return_request_id INTEGER NOT NULL,
+    order_item_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    variant_id INTEGER NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL,
+    reason TEXT,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (return_request_id) REFERENCES return_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    return_request_id INTEGER NOT NULL UNIQUE,
+    order_id INTEGER NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    status VARCHAR(50) DEFAULT 'pending', -- pending, processed, manual
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (return_request_id) REFERENCES return_requests(id),
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_return_requests_order_id ON return_requests(order_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_user_id ON return_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_status ON return_requests(status);
+CREATE INDEX IF NOT EXISTS idx_return_items_return_request_id ON return_items(return_request_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds(order_id);
+
+-- ============================================
+-- 10. COUPONS TABLES
+-- ============================================
+CREATE TABLE IF NOT EXISTS coupons (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(100) UNIQUE NOT NULL,
+    type VARCHAR(20) NOT NULL, -- percentage, fixed
+    value DECIMAL(5, 2) DEFAULT 0, -- percent, for percentage coupons
+    amount_amount BIGINT NOT NULL DEFAULT 0, -- for fixed coupons
+    amount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    min_spend_amount BIGINT NOT NULL DEFAULT 0,
+    min_spend_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    max_uses INTEGER DEFAULT 0, -- 0 = unlimited
+    per_user_limit INTEGER DEFAULT 0, -- 0 = unlimited
+    used_count INTEGER DEFAULT 0,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS coupon_products (
+    id SERIAL PRIMARY KEY,
+    coupon_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id SERIAL PRIMARY KEY,
+    coupon_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    order_id INTEGER NOT NULL,
+    discount_amount BIGINT NOT NULL DEFAULT 0,
+    discount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_products_coupon_id ON coupon_products(coupon_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_id ON coupon_redemptions(coupon_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_user_id ON coupon_redemptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_order_id ON coupon_redemptions(order_id);
+
+-- ============================================
+-- 11. TAX TABLES
+-- ============================================
+CREATE TABLE IF NOT EXISTS tax_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    tax_class VARCHAR(50), -- empty matches every tax class
+    region VARCHAR(50), -- empty matches every region
+    rate DECIMAL(5, 2) NOT NULL, -- percent
+    active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS order_taxes (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    tax_rule_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    rate DECIMAL(5, 2) NOT NULL,
+    taxable_amount BIGINT NOT NULL DEFAULT 0,
+    taxable_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_rules_tax_class ON tax_rules(tax_class);
+CREATE INDEX IF NOT EXISTS idx_tax_rules_region ON tax_rules(region);
+CREATE INDEX IF NOT EXISTS idx_order_taxes_order_id ON order_taxes(order_id);
+
+-- ============================================
+-- 12. SHIPPING TABLES
+-- ============================================
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A zone without regions is the fallback for unlisted regions
+CREATE TABLE IF NOT EXISTS shipping_zone_regions (
+    id SERIAL PRIMARY KEY,
+    zone_id INTEGER NOT NULL,
+    region VARCHAR(50) UNIQUE NOT NULL,
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id SERIAL PRIMARY KEY,
+    zone_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    flat_fee_amount BIGINT NOT NULL DEFAULT 0,
+    flat_fee_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    free_threshold_amount BIGINT NOT NULL DEFAULT 0, -- 0 = never free
+    free_threshold_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(id) ON DELETE CASCADE
+);
+
+-- Weight brackets: a parcel uses the first bracket with max_weight >= its weight
+CREATE TABLE IF NOT EXISTS shipping_rates (
+    id SERIAL PRIMARY KEY,
+    method_id INTEGER NOT NULL,
+    max_weight DECIMAL(10, 2) NOT NULL, -- in kg
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    FOREIGN KEY (method_id) REFERENCES shipping_methods(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_zone_regions_zone_id ON shipping_zone_regions(zone_id);
+CREATE INDEX IF NOT EXISTS idx_shipping_methods_zone_id ON shipping_methods(zone_id);
+CREATE INDEX IF NOT EXISTS idx_shipping_rates_method_id ON shipping_rates(method_id);
+
+-- ============================================
+-- 13. CATEGORY TABLES
+-- ============================================
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER, -- NULL for top-level categories
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    position INTEGER NOT NULL DEFAULT 0, -- order among siblings
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (parent_id) REFERENCES categories(id)
+);
+
+-- A product can be in many categories
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    PRIMARY KEY (product_id, category_id),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+CREATE INDEX IF NOT EXISTS idx_product_categories_category_id ON product_categories(category_id);
+
+-- ============================================
+-- 14. PRODUCT VARIANTS TABLE
+-- ============================================
+-- Variants of a product, e.g. each colour and size of a shirt. A product
+-- with variants is sold only through them, and each keeps its own stock.
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    sku VARCHAR(100) UNIQUE NOT NULL,
+    attributes JSONB, -- e.g. {"colour": "red", "size": "M"}
+    attribute_key VARCHAR(255) NOT NULL, -- normalised attributes, unique per product
+    price_override_amount BIGINT NOT NULL DEFAULT 0, -- 0 = product price
+    price_override_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    weight_override DECIMAL(10, 2) NOT NULL DEFAULT 0, -- 0 = product weight
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_attributes ON product_variants(product_id, attribute_key);
+
+-- ============================================
+-- 15. PRODUCT IMAGES TABLE
+-- ============================================
+-- Gallery images of a product. The files (the original and its small,
+-- medium and large thumbnails) are kept in upload storage; only the
+-- original's storage key is saved here.
+
+CREATE TABLE IF NOT EXISTS product_images (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0, -- gallery order, from 0
+    key VARCHAR(255) NOT NULL, -- e.g. products/12/4f9c0e.jpg
+    content_type VARCHAR(50),
+    width INTEGER,
+    height INTEGER,
+    alt_text VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images(product_id);
+
+-- ============================================
+-- 16. REVIEWS TABLE
+-- ============================================
+-- Customer ratings of products they received in a delivered order, one per
+-- customer and product. Reviews wait for moderation; only approved ones are
+-- shown and counted in products.rating_average and products.rating_count.
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title VARCHAR(200) NOT NULL,
+    body TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, approved, rejected, hidden
+    moderation_note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_product_user ON reviews(product_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews(user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews(status);
+
+-- ============================================
+-- 17. PRODUCT PRICE HISTORY TABLE
+-- ============================================
+-- One row per price a product has had. A new row is added whenever the price
+-- changes, ending the previous one; the current price has no effective_to.
+-- changed_by is the admin who set the price, or 0 for command-line imports
+-- and prices from before history was kept. Order items keep their own copy
+-- of the price paid.
+
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    price_amount BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP,
+    changed_by INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_history_product ON product_price_history(product_id, effective_from);
+
+-- ============================================
+-- 18. SALES TABLES
+-- ============================================
+-- Scheduled sales that lower prices between starts_at and ends_at, by a
+-- percentage, a fixed amount or to a set price. A sale covers the products in
+-- sale_products and every product in the categories in sale_categories or
+-- their subcategories. Cancelled sales keep their row with cancelled_at set.
+
+CREATE TABLE IF NOT EXISTS sales (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL, -- percentage, fixed, price
+    value DECIMAL(5, 2) DEFAULT 0, -- percent, for percentage sales
+    amount_amount BIGINT NOT NULL DEFAULT 0, -- for fixed and price sales
+    amount_currency VARCHAR(3) NOT NULL DEFAULT 'BDT',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    cancelled_at TIMESTAMP,
+    created_by INTEGER, -- admin ID
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_starts_at ON sales(starts_at);
+CREATE INDEX IF NOT EXISTS idx_sales_ends_at ON sales(ends_at);
+
+CREATE TABLE IF NOT EXISTS sale_products (
+    id SERIAL PRIMARY KEY,
+    sale_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    FOREIGN KEY (sale_id) REFERENCES sales(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sale_products_sale_id ON sale_products(sale_id);
+CREATE INDEX IF NOT EXISTS idx_sale_products_product_id ON sale_products(product_id);
+
+CREATE TABLE IF NOT EXISTS sale_categories (
+    id SERIAL PRIMARY KEY,
+    sale_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    FOREIGN KEY (sale_id) REFERENCES sales(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sale_categories_sale_id ON sale_categories(sale_id);
+CREATE INDEX IF NOT EXISTS idx_sale_categories_category_id ON sale_categories(category_id);
 
 -- ============================================
 -- SAMPLE DATA
@@ -79,10 +543,30 @@ INSERT INTO admins (username, password, email, role) VALUES
 ('superadmin', 'admin123', 'superadmin@example.com', 'super_admin');
 
 -- Insert Products
-INSERT INTO products (name, price, weight, colour, description) VALUES
-('Apple', 150, 0.5, 'green', 'Fresh green apple'),
-('Orange', 200, 0.4, 'orange', 'Fresh orange fruit'),
-('Banana', 100, 0.3, 'yellow', 'Sweet yellow banana');
+INSERT INTO products (name, price_amount, weight, colour, description, stock) VALUES
+('Apple', 15000, 0.5, 'green', 'Fresh green apple', 100),
+('Orange', 20000, 0.4, 'orange', 'Fresh orange fruit', 80),
+('Banana', 10000, 0.3, 'yellow', 'Sweet yellow banana', 120);
+
+-- Index the sample products for full-text search
+UPDATE products SET search_vector =
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+WHERE search_vector IS NULL;
+
+-- Open the sample products' price history
+INSERT INTO product_price_history (product_id, price_amount, price_currency, effective_from)
+SELECT id, price_amount, price_currency, created_at FROM products;
+
+-- Insert Categories
+INSERT INTO categories (parent_id, name, slug, position) VALUES
+(NULL, 'Fruit', 'fruit', 0),
+(1, 'Citrus', 'citrus', 0);
+
+INSERT INTO product_categories (product_id, category_id) VALUES
+(1, 1),
+(2, 2),
+(3, 1);
 
 -- Insert Users
 INSERT INTO users (name, email, phone, password, address) VALUES
@@ -90,9 +574,14 @@ INSERT INTO users (name, email, phone, password, address) VALUES
 ('Fatima Begum', 'fatima@example.com', '01987654321', 'password123', 'Chittagong, Bangladesh');
 
 -- Insert Orders
-INSERT INTO orders (user_id, product_id, quantity, total_price, status) VALUES
-(1, 1, 2, 300, 'pending'),
-(2, 2, 3, 600, 'confirmed');
+INSERT INTO orders (user_id, subtotal_amount, total_price_amount, status) VALUES
+(1, 30000, 30000, 'pending'),
+(2, 60000, 60000, 'confirmed');
+
+-- Insert Order Items
+INSERT INTO order_items (order_id, product_id, name, price_amount, quantity, total_amount) VALUES
+(1, 1, 'Apple', 15000, 2, 30000),
+(2, 2, 'Orange', 20000, 3, 60000);
 
 -- ============================================
 -- VERIFY DATA
@@ -103,4 +592,6 @@ SELECT 'Products', COUNT(*) FROM products
 UNION ALL
 SELECT 'Users', COUNT(*) FROM users
 UNION ALL
-SELECT 'Orders', COUNT(*) FROM orders;
+SELECT 'Orders', COUNT(*) FROM orders
+UNION ALL
+SELECT 'Order Items', COUNT(*) FROM order_items;